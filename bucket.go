@@ -0,0 +1,238 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+BucketRateLimiter enforces a rate limit on quantities using a token bucket, and satisfies the RateLimiter, WeightedRateLimiter and InvocationLimiter interfaces.
+
+The bucket refills continuously at the limiter's rate and holds at most the burst size. A quantity larger than the available budget is permitted after waiting for the shortfall to refill, so callers are never blocked forever by quantities larger than the burst size.
+*/
+type BucketRateLimiter struct {
+	mu          sync.Mutex
+	clock       Clock
+	rate        Rate
+	burst       int
+	available   float64
+	last        time.Time
+	panicPolicy PanicPolicy
+}
+
+/*
+NewBucketRateLimiter instantiates a new BucketRateLimiter with the provided rate and burst size. If burst is not positive, the rate's count is used as the burst size.
+
+The bucket starts full. It panics with an *OptionError if the rate or options are invalid; BuildBucketRateLimiter returns the error instead.
+*/
+func NewBucketRateLimiter(maxRate Rate, burst int, opts ...Option) (l *BucketRateLimiter) {
+	if burst < 0 {
+		burst = 0
+	}
+	l, err := BuildBucketRateLimiter(append([]Option{WithRate(maxRate), WithBurst(burst)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildBucketRateLimiter instantiates a new BucketRateLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithRate is required. WithBurst sets the burst size, which defaults to the rate's count.
+*/
+func BuildBucketRateLimiter(opts ...Option) (l *BucketRateLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBurst"), o.validateClock(), o.validateRate()); err != nil {
+		return
+	}
+	if o.burst < 0 {
+		err = &OptionError{"WithBurst", ErrInvalidBurst}
+		return
+	}
+	l = &BucketRateLimiter{
+		clock:       o.clock,
+		rate:        o.rate,
+		burst:       o.burst,
+		panicPolicy: o.panicPolicy,
+	}
+	l.available = l.capacity()
+	return
+}
+
+/*
+CheckWait consumes a quantity of 1 from the limiter's budget. It blocks if the budget is exhausted, otherwise it returns immediately.
+*/
+func (l *BucketRateLimiter) CheckWait() {
+	l.CheckWaitN(1)
+}
+
+/*
+CheckWaitN consumes the provided quantity from the limiter's budget. It blocks until the budget has refilled enough to cover any shortfall, otherwise it returns immediately.
+*/
+func (l *BucketRateLimiter) CheckWaitN(n int) {
+	if d := l.reserve(n); d > 0 {
+		l.clock.Sleep(d)
+	}
+}
+
+/*
+WaitN consumes the provided quantity from the limiter's budget, blocking until the budget has refilled enough to cover any shortfall or until the context is done.
+
+If the context is done before the wait completes, the quantity is returned to the budget and the context's error is returned.
+*/
+func (l *BucketRateLimiter) WaitN(ctx context.Context, n int) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if err = l.waitReserved(ctx, l.reserve(n)); err != nil {
+		l.cancel(n)
+	}
+	return
+}
+
+// waitReserved waits for a reservation to be covered by the budget, returning the context's error if it is done first; the caller decides whether to cancel the reservation.
+func (l *BucketRateLimiter) waitReserved(ctx context.Context, d time.Duration) (err error) {
+	if d <= 0 {
+		return
+	}
+	timer := l.clock.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		err = ctx.Err()
+	case <-timer.C():
+	}
+	return
+}
+
+/*
+Invoke enforces the limiter's limits around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification, and its existence may be used by the limiter to delay the current return or subsequent invocations.
+*/
+func (l *BucketRateLimiter) Invoke(f func() error) (err error) {
+	l.CheckWait()
+	return l.panicPolicy.invoke(f, nil)
+}
+
+/*
+SetMaxRate sets a new refill rate for this limiter. The current budget is preserved.
+*/
+func (l *BucketRateLimiter) SetMaxRate(rate Rate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.clock.Now())
+	l.rate = rate
+	if capacity := l.capacity(); l.available > capacity {
+		l.available = capacity
+	}
+}
+
+/*
+SetBurst sets a new burst size for this limiter. If burst is not positive, the current rate's count is used. The current budget is reduced if it exceeds the new burst size.
+*/
+func (l *BucketRateLimiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.clock.Now())
+	l.burst = burst
+	if capacity := l.capacity(); l.available > capacity {
+		l.available = capacity
+	}
+}
+
+/*
+Reconfigure applies the WithRate and WithBurst options to this limiter, preserving the current budget up to the new burst size. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *BucketRateLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBurst"), o.validateSet()); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.clock.Now())
+	if o.rateSet {
+		l.rate = o.rate
+	}
+	if o.burstSet {
+		l.burst = o.burst
+	}
+	if capacity := l.capacity(); l.available > capacity {
+		l.available = capacity
+	}
+	return
+}
+
+// reserve deducts the quantity from the budget and returns how long the caller must wait for the budget to cover it.
+func (l *BucketRateLimiter) reserve(n int) (wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.clock.Now())
+	l.available -= float64(n)
+	if l.available < 0 {
+		wait = time.Duration(-l.available / l.perNanos())
+	}
+	return
+}
+
+// cancel returns a reserved quantity to the budget.
+func (l *BucketRateLimiter) cancel(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.available += float64(n)
+	if capacity := l.capacity(); l.available > capacity {
+		l.available = capacity
+	}
+}
+
+// capacity returns the burst size, which defaults to the rate's count.
+func (l *BucketRateLimiter) capacity() float64 {
+	if l.burst > 0 {
+		return float64(l.burst)
+	}
+	return float64(l.rate.Count)
+}
+
+func (l *BucketRateLimiter) perNanos() float64 {
+	return float64(l.rate.Count) / float64(l.rate.Duration)
+}
+
+func (l *BucketRateLimiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.available += float64(now.Sub(l.last)) * l.perNanos()
+		if capacity := l.capacity(); l.available > capacity {
+			l.available = capacity
+		}
+	}
+	l.last = now
+}
+
+/*
+MarshalBinary encodes the limiter's budget and the time it was last refilled, satisfying the encoding.BinaryMarshaler interface.
+*/
+func (l *BucketRateLimiter) MarshalBinary() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := newStateEncoder(bucketRateState)
+	e.float(l.available)
+	e.time(l.last)
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores a budget encoded by MarshalBinary. The budget is refilled for the time which has passed since it was encoded, up to the burst size.
+*/
+func (l *BucketRateLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, bucketRateState)
+	available, last := d.float(), d.time()
+	if err = d.finish(); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.available = available
+	l.last = last
+	if capacity := l.capacity(); l.available > capacity {
+		l.available = capacity
+	}
+	return
+}
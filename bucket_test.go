@@ -0,0 +1,98 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucketRateLimiter(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBucketRateLimiter(NewRate(1000, time.Second), 10, WithClock(c))
+
+	start := c.Now()
+	l.CheckWaitN(10)
+	if actual := c.Sleepers(); actual != 0 {
+		t.Fatalf("Expected burst without delay, got %d sleepers", actual)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 4; i += 1 {
+			l.CheckWaitN(10)
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
+
+	expected := time.Duration(40) * time.Millisecond
+	if duration != expected {
+		t.Fatalf("Expected duration %d, got %d", expected, duration)
+	}
+}
+
+func TestBucketRateLimiter_LargeQuantity(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBucketRateLimiter(NewRate(1000, time.Second), 10, WithClock(c))
+
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		l.CheckWaitN(40)
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
+
+	expected := time.Duration(30) * time.Millisecond
+	if duration != expected {
+		t.Fatalf("Expected duration %d, got %d", expected, duration)
+	}
+}
+
+func TestBucketRateLimiter_Invoke(t *testing.T) {
+	l := NewBucketRateLimiter(NewRate(1, time.Millisecond), 0)
+
+	if err := l.Invoke(func() error { return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if err := l.Invoke(func() error { return nil }); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestBucketRateLimiter_Reconfigure(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBucketRateLimiter(NewRate(10, time.Second), 10, WithClock(c))
+
+	l.CheckWaitN(4)
+	if err := l.Reconfigure(WithRate(NewRate(100, time.Second)), WithBurst(100)); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if l.available != 6 {
+		t.Errorf("Expected budget of 6 to be preserved, got %f", l.available)
+	}
+	if err := l.Reconfigure(WithBurst(2)); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if l.available != 2 {
+		t.Errorf("Expected budget clamped to 2, got %f", l.available)
+	}
+
+	if err := l.Reconfigure(WithRate(NewRate(1, time.Second)), WithBurst(-1)); !errors.Is(err, ErrInvalidBurst) {
+		t.Fatalf("Expected '%v', got '%v'", ErrInvalidBurst, err)
+	}
+	if l.rate != NewRate(100, time.Second) {
+		t.Errorf("Expected rate to be unchanged, got %v", l.rate)
+	}
+}
+
+func BenchmarkBucketRateLimiter(b *testing.B) {
+	l := NewBucketRateLimiter(NewRate(2000000, time.Millisecond), 0)
+
+	for i := 0; i < b.N; i++ {
+		l.CheckWaitN(1)
+	}
+}
@@ -1,29 +1,60 @@
 package limiter
 
 import (
+	"sync"
 	"time"
 
 	"github.com/momokatte/go-backoff"
-	vrl "github.com/youtube/vitess/go/ratelimiter"
 )
 
 /*
 BurstRateLimiter enforces a rate limit within an interval and satisfies the RateLimiter and InvocationLimiter interfaces.
 */
 type BurstRateLimiter struct {
-	rateLimiter *vrl.RateLimiter
+	mu          sync.Mutex
+	clock       Clock
+	maxCount    int
+	interval    time.Duration
+	count       int
+	start       time.Time
 	backOffFunc func(uint) uint
+	backOffSet  bool
+	panicPolicy PanicPolicy
 }
 
 /*
 NewBurstRateLimiter instantiates a BurstRateLimiter with the provided rate threshold and a wait-backoff function with full jitter appropriate for high-frequency use (more than 200 actions per second).
+
+The rate is not validated, for compatibility with earlier versions; BuildBurstRateLimiter rejects an invalid rate. NewBurstRateLimiter panics with an *OptionError only if an option is invalid.
+*/
+func NewBurstRateLimiter(maxRate Rate, opts ...Option) (l *BurstRateLimiter) {
+	o := newOptions(opts)
+	must(firstError(o.validateApplicable("WithBackOff"), o.validateClock(), o.validateSet()))
+	return newBurstRateLimiter(maxRate, o)
+}
+
+/*
+BuildBurstRateLimiter instantiates a BurstRateLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithRate is required. WithBackOff replaces the default wait-backoff function, which has full jitter appropriate for high-frequency use.
 */
-func NewBurstRateLimiter(maxRate Rate) (l *BurstRateLimiter) {
-	l = &BurstRateLimiter{}
+func BuildBurstRateLimiter(opts ...Option) (l *BurstRateLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBackOff"), o.validateClock(), o.validateRate(), o.validateBackOff(false)); err != nil {
+		return
+	}
+	l = newBurstRateLimiter(o.rate, o)
+	return
+}
+
+func newBurstRateLimiter(maxRate Rate, o options) (l *BurstRateLimiter) {
+	l = &BurstRateLimiter{
+		clock:       o.clock,
+		backOffFunc: o.backOffFunc,
+		backOffSet:  o.backOffSet,
+		panicPolicy: o.panicPolicy,
+	}
 	l.SetMaxRate(maxRate)
-	// jitter smooths out retries -- this minimum value is for high-frequency use
-	// TODO: calculate minimum based on provided rate
-	l.backOffFunc = backoff.FullJitter(uint(time.Millisecond/2), uint(maxRate.Duration))
 	return
 }
 
@@ -34,28 +65,121 @@ If calls to this method are uniform, the allowed rate will roughly match the rat
 */
 func (l *BurstRateLimiter) CheckWait() {
 	// retry with backoff until allowed
-	for fails := uint(0); !l.rateLimiter.Allow(); {
+	for fails := uint(0); !l.Allow(); {
 		fails += 1
-		sleep := l.backOffFunc(fails)
-		time.Sleep(time.Duration(sleep) * time.Nanosecond)
+		l.mu.Lock()
+		backOffFunc := l.backOffFunc
+		l.mu.Unlock()
+		sleep := backOffFunc(fails)
+		l.clock.Sleep(time.Duration(sleep) * time.Nanosecond)
 	}
 	return
 }
 
+/*
+Allow consumes from the limiter's rate budget and returns true if the budget permits an action, otherwise it returns false without blocking.
+*/
+func (l *BurstRateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if now.Sub(l.start) < l.interval {
+		if l.count < l.maxCount {
+			l.count += 1
+			return true
+		}
+		return false
+	}
+	// begin a new interval
+	l.start = now
+	l.count = 1
+	return true
+}
+
+/*
+Refund returns one action to the current interval's rate budget, for an action which was allowed but then abandoned before it was taken.
+*/
+func (l *BurstRateLimiter) Refund() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.count > 0 {
+		l.count -= 1
+	}
+}
+
 /*
 Invoke enforces the limiter's limits around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification, and its existence may be used by the limiter to delay the current return or subsequent invocations.
 */
 func (l *BurstRateLimiter) Invoke(f func() error) (err error) {
 	l.CheckWait()
-	err = f()
-	return
+	return l.panicPolicy.invoke(f, nil)
 }
 
 /*
-SetRateLimit sets a new rate threshold for this limiter.
+SetMaxRate sets a new rate threshold for this limiter.
 
-It does not make any adjustment based on the previous threshold, so the rate allowed immediately before and after this change may together exceed the specified threshold.
+The current interval and the actions already allowed within it are preserved, so the new threshold applies to the remainder of the current interval.
 */
 func (l *BurstRateLimiter) SetMaxRate(rate Rate) {
-	l.rateLimiter = vrl.NewRateLimiter(rate.Count, rate.Duration)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setMaxRate(rate)
+}
+
+/*
+Reconfigure applies the WithRate and WithBackOff options to this limiter, preserving the current interval and its usage. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *BurstRateLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBackOff"), o.validateSet()); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if o.backOffSet {
+		l.backOffFunc = o.backOffFunc
+		l.backOffSet = true
+	}
+	if o.rateSet {
+		l.setMaxRate(o.rate)
+	}
+	return
+}
+
+func (l *BurstRateLimiter) setMaxRate(rate Rate) {
+	l.maxCount = rate.Count
+	l.interval = rate.Duration
+	if !l.backOffSet {
+		// jitter smooths out retries -- this minimum value is for high-frequency use
+		// TODO: calculate minimum based on provided rate
+		l.backOffFunc = backoff.FullJitter(uint(time.Millisecond/2), uint(rate.Duration))
+	}
+}
+
+/*
+MarshalBinary encodes the start and usage of the limiter's current interval, satisfying the encoding.BinaryMarshaler interface.
+*/
+func (l *BurstRateLimiter) MarshalBinary() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := newStateEncoder(burstRateState)
+	e.time(l.start)
+	e.int(int64(l.count))
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores an interval encoded by MarshalBinary. If the interval has since ended, the next action begins a new one.
+*/
+func (l *BurstRateLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, burstRateState)
+	start, count := d.time(), d.int()
+	if err = d.finish(); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.start = start
+	l.count = int(count)
+	return
 }
@@ -7,54 +7,118 @@ import (
 )
 
 func TestBurstRateLimiter(t *testing.T) {
-	l := NewBurstRateLimiter(NewRate(1, time.Millisecond))
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	// a fixed wait backoff of one interval makes the elapsed time exact
+	l := NewBurstRateLimiter(NewRate(1, time.Millisecond), WithClock(c), WithBackOff(func(uint) uint { return uint(time.Millisecond) }))
 
-	start := time.Now()
-	for i := 0; i < 40; i += 1 {
-		l.CheckWait()
-	}
-	duration := time.Now().Sub(start)
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		for i := 0; i < 40; i += 1 {
+			l.CheckWait()
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, 100*time.Microsecond, done)
+	duration := c.Now().Sub(start)
 
-	expectedMin := time.Duration(30) * time.Millisecond
-	if duration < expectedMin {
-		t.Errorf("Expected duration greater than %d, got %d", expectedMin, duration)
-		t.FailNow()
+	expected := time.Duration(39) * time.Millisecond
+	if duration != expected {
+		t.Errorf("Expected duration %d, got %d", expected, duration)
 	}
+}
 
-	expectedMax := time.Duration(50) * time.Millisecond
-	if expectedMax < duration {
-		t.Errorf("Expected duration less than %d, got %d", expectedMax, duration)
-		t.FailNow()
+func TestBurstRateLimiter_Allow(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBurstRateLimiter(NewRate(2, time.Second), WithClock(c))
+
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("Expected first 2 actions to be allowed")
+	}
+	if l.Allow() {
+		t.Fatal("Expected third action to be denied")
+	}
+	c.Advance(time.Second)
+	if !l.Allow() {
+		t.Fatal("Expected action in new interval to be allowed")
 	}
 }
 
 func TestBurstRateLimiter_Invoke(t *testing.T) {
-	l := NewBurstRateLimiter(NewRate(1, time.Millisecond))
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	// a fixed wait backoff of one interval makes the elapsed time exact
+	l := NewBurstRateLimiter(NewRate(1, time.Millisecond), WithClock(c), WithBackOff(func(uint) uint { return uint(time.Millisecond) }))
 
 	if err := l.Invoke(func() error { return errors.New("error") }); err == nil {
 		t.Error("Expected error, got nil")
 	}
 
-	if err := l.Invoke(func() error { return nil }); err != nil {
-		t.Errorf("Unexpected error, got: %s", err.Error())
+	done := make(chan struct{})
+	go func() {
+		if err := l.Invoke(func() error { return nil }); err != nil {
+			t.Errorf("Unexpected error, got: %s", err.Error())
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, 100*time.Microsecond, done)
+
+	done = make(chan struct{})
+	start := c.Now()
+	go func() {
+		for i := 0; i < 40; i += 1 {
+			l.Invoke(func() error { return nil })
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, 100*time.Microsecond, done)
+	duration := c.Now().Sub(start)
+
+	// the first invocation also waits for the interval begun by the previous one
+	expected := time.Duration(40) * time.Millisecond
+	if duration != expected {
+		t.Errorf("Expected duration %d, got %d", expected, duration)
 	}
+}
 
-	start := time.Now()
-	for i := 0; i < 40; i += 1 {
-		l.Invoke(func() error { return nil })
+func TestBurstRateLimiter_Reconfigure(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBurstRateLimiter(NewRate(2, time.Second), WithClock(c))
+
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("Expected first 2 actions to be allowed")
+	}
+	if err := l.Reconfigure(WithRate(NewRate(3, time.Second))); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if !l.Allow() {
+		t.Fatal("Expected third action to be allowed by the new rate")
+	}
+	if l.Allow() {
+		t.Fatal("Expected fourth action to be denied within the same interval")
 	}
-	duration := time.Now().Sub(start)
 
-	expectedMin := time.Duration(30) * time.Millisecond
-	if duration < expectedMin {
-		t.Errorf("Expected duration greater than %d, got %d", expectedMin, duration)
-		t.FailNow()
+	var oe *OptionError
+	if err := l.Reconfigure(WithRate(NewRate(0, time.Second))); !errors.As(err, &oe) || !errors.Is(err, ErrInvalidRate) {
+		t.Fatalf("Expected invalid rate, got '%v'", err)
+	}
+	if l.maxCount != 3 {
+		t.Errorf("Expected 3, got %d", l.maxCount)
 	}
+}
+
+func TestBurstRateLimiter_Refund(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBurstRateLimiter(NewRate(1, time.Second), WithClock(c))
 
-	expectedMax := time.Duration(50) * time.Millisecond
-	if expectedMax < duration {
-		t.Errorf("Expected duration less than %d, got %d", expectedMax, duration)
-		t.FailNow()
+	if !l.Allow() {
+		t.Fatal("Expected action to be allowed")
+	}
+	l.Refund()
+	if !l.Allow() {
+		t.Error("Expected refunded action to be allowed")
+	}
+	if l.Allow() {
+		t.Error("Expected action to be denied")
 	}
 }
 
@@ -0,0 +1,41 @@
+package limiter
+
+/*
+ChainLimiter composes InvocationLimiters so that each one enforces its limits around the invocations of the next, and satisfies the InvocationLimiter interface.
+*/
+type ChainLimiter struct {
+	limiters []InvocationLimiter
+}
+
+/*
+NewChainLimiter instantiates a new ChainLimiter with the provided InvocationLimiters, outermost first. For example, a BurstRateLimiter followed by a TokenChanLimiter and a FailBackOffLimiter checks the rate before acquiring a token, and backs off while holding the token.
+*/
+func NewChainLimiter(ls ...InvocationLimiter) (l *ChainLimiter) {
+	l = &ChainLimiter{
+		limiters: ls,
+	}
+	return
+}
+
+/*
+Limiters returns the chained InvocationLimiters, outermost first.
+*/
+func (l *ChainLimiter) Limiters() []InvocationLimiter {
+	return l.limiters
+}
+
+/*
+Invoke enforces the limits of every chained limiter around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification, and its existence may be used by the limiters to delay the current return or subsequent invocations.
+*/
+func (l *ChainLimiter) Invoke(f func() error) error {
+	return l.invoke(0, f)
+}
+
+func (l *ChainLimiter) invoke(i int, f func() error) error {
+	if i == len(l.limiters) {
+		return f()
+	}
+	return l.limiters[i].Invoke(func() error {
+		return l.invoke(i+1, f)
+	})
+}
@@ -0,0 +1,32 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+)
+
+type recordingLimiter struct {
+	name  string
+	calls *[]string
+}
+
+func (l recordingLimiter) Invoke(f func() error) error {
+	*l.calls = append(*l.calls, l.name)
+	return f()
+}
+
+func TestChainLimiter_Invoke(t *testing.T) {
+	var calls []string
+	l := NewChainLimiter(recordingLimiter{"a", &calls}, recordingLimiter{"b", &calls})
+
+	if err := l.Invoke(func() error { calls = append(calls, "f"); return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if len(calls) != 3 || calls[0] != "a" || calls[1] != "b" || calls[2] != "f" {
+		t.Errorf("Expected [a b f], got %v", calls)
+	}
+
+	if err := NewChainLimiter().Invoke(func() error { return nil }); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
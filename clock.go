@@ -0,0 +1,181 @@
+package limiter
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Clock is the interface that wraps the time functions used by limiters, so that the passage of time can be controlled in tests.
+
+Now returns the current time. Sleep blocks for the provided duration. NewTimer returns a Timer which delivers the current time on its channel after the provided duration.
+*/
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+}
+
+/*
+Timer is the interface that wraps the C and Stop methods of a single-use timer created by a Clock.
+
+C returns the channel on which the time is delivered when the timer fires. Stop prevents the timer from firing, returning false if it has already fired or been stopped.
+*/
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+/*
+RealClock is a Clock backed by the time package. It is the default Clock for all limiters.
+*/
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+/*
+FakeClock is a Clock whose time only changes when Advance is called, for deterministic tests.
+
+Goroutines blocked in Sleep and pending timers are both counted as sleepers, which tests can wait for with BlockUntil before advancing the clock.
+*/
+type FakeClock struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	now      time.Time
+	sleepers []*fakeTimer
+}
+
+/*
+NewFakeClock instantiates a new FakeClock set to the provided time.
+*/
+func NewFakeClock(now time.Time) (c *FakeClock) {
+	c = &FakeClock{
+		now: now,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return
+}
+
+/*
+Now returns the clock's current time.
+*/
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+/*
+Sleep blocks until the clock has been advanced by at least the provided duration. It returns immediately if the duration is not positive.
+*/
+func (c *FakeClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-c.NewTimer(d).C()
+}
+
+/*
+NewTimer returns a Timer which fires when the clock has been advanced by at least the provided duration. A timer with a non-positive duration fires immediately.
+*/
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.sleepers = append(c.sleepers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+/*
+Advance moves the clock forward by the provided duration, waking sleepers and firing timers whose deadlines have been reached, in deadline order.
+*/
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.sleepers, func(i, j int) bool {
+		return c.sleepers[i].deadline.Before(c.sleepers[j].deadline)
+	})
+	i := 0
+	for ; i < len(c.sleepers) && !c.sleepers[i].deadline.After(c.now); i += 1 {
+		c.sleepers[i].c <- c.now
+	}
+	c.sleepers = c.sleepers[i:]
+	c.cond.Broadcast()
+}
+
+/*
+Sleepers returns the number of goroutines blocked in Sleep plus the number of pending timers.
+*/
+func (c *FakeClock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+/*
+BlockUntil blocks until at least the provided number of goroutines are blocked in Sleep or timers are pending.
+*/
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.sleepers) < n {
+		c.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, s := range c.sleepers {
+		if s == t {
+			c.sleepers = append(c.sleepers[:i], c.sleepers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package limiter
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// advanceWhileSleeping advances the clock by step whenever a goroutine is sleeping or a timer is pending, until done is closed.
+func advanceWhileSleeping(c *FakeClock, step time.Duration, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if c.Sleepers() > 0 {
+			c.Advance(step)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(10 * time.Millisecond)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(9 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Expected sleeper to be blocked")
+	default:
+	}
+	c.Advance(time.Millisecond)
+	<-done
+
+	if actual := c.Now().Sub(start); actual != 10*time.Millisecond {
+		t.Errorf("Expected %s, got %s", 10*time.Millisecond, actual)
+	}
+	if actual := c.Sleepers(); actual != 0 {
+		t.Errorf("Expected 0, got %d", actual)
+	}
+}
+
+func TestFakeClock_Timer(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	a := c.NewTimer(time.Second)
+	b := c.NewTimer(2 * time.Second)
+	if actual := c.Sleepers(); actual != 2 {
+		t.Fatalf("Expected 2, got %d", actual)
+	}
+	if !b.Stop() {
+		t.Error("Expected Stop to return true for a pending timer")
+	}
+
+	c.Advance(3 * time.Second)
+	select {
+	case <-a.C():
+	default:
+		t.Error("Expected timer to fire")
+	}
+	select {
+	case <-b.C():
+		t.Error("Expected stopped timer not to fire")
+	default:
+	}
+	if a.Stop() {
+		t.Error("Expected Stop to return false for a fired timer")
+	}
+
+	select {
+	case <-c.NewTimer(0).C():
+	default:
+		t.Error("Expected timer with zero duration to fire immediately")
+	}
+}
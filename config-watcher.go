@@ -0,0 +1,88 @@
+package limiter
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+/*
+ConfigWatcher periodically loads a Config and applies it to a LimiterSet when it changes, so limits can be tuned without restarting the process.
+*/
+type ConfigWatcher struct {
+	mu       sync.Mutex
+	clock    Clock
+	set      *LimiterSet
+	load     func() (*Config, error)
+	interval time.Duration
+	last     *Config
+	poll     poller
+}
+
+/*
+NewConfigWatcher instantiates a new ConfigWatcher which calls the load function at the provided interval and applies the result to the LimiterSet. The watcher does not poll until Start is called.
+*/
+func NewConfigWatcher(s *LimiterSet, load func() (*Config, error), interval time.Duration, opts ...Option) (w *ConfigWatcher) {
+	w = &ConfigWatcher{
+		clock:    newOptions(opts).clock,
+		set:      s,
+		load:     load,
+		interval: interval,
+	}
+	return
+}
+
+/*
+ConfigFileLoader returns a load function for a ConfigWatcher which reads and parses the JSON config file at the provided path.
+*/
+func ConfigFileLoader(path string) func() (*Config, error) {
+	return func() (c *Config, err error) {
+		var b []byte
+		if b, err = os.ReadFile(path); err != nil {
+			return
+		}
+		return ParseConfig(b)
+	}
+}
+
+/*
+SetErrorHandler sets a function which is called with errors from loading or applying a Config while polling. Errors leave the LimiterSet unchanged.
+*/
+func (w *ConfigWatcher) SetErrorHandler(f func(error)) {
+	w.poll.setErrorHandler(f)
+}
+
+/*
+Reload loads the Config immediately and applies it to the LimiterSet if it differs from the last Config applied by the watcher.
+*/
+func (w *ConfigWatcher) Reload() (err error) {
+	var c *Config
+	if c, err = w.load(); err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last != nil && reflect.DeepEqual(w.last, c) {
+		return
+	}
+	if err = w.set.Apply(c); err != nil {
+		return
+	}
+	w.last = c
+	return
+}
+
+/*
+Start begins polling in a new goroutine. Calling Start on a watcher which is already polling has no effect. ErrInvalidInterval is returned if the watcher's interval is not positive.
+*/
+func (w *ConfigWatcher) Start() error {
+	return w.poll.start(w.clock, w.interval, w.Reload)
+}
+
+/*
+Stop ends polling and waits for any reload in progress to complete.
+*/
+func (w *ConfigWatcher) Stop() {
+	w.poll.halt()
+}
@@ -0,0 +1,57 @@
+package limiter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	write := func(rate string) {
+		b := []byte(`{"limiters": {"api": {"stages": [{"type": "burst-rate", "rate": "` + rate + `"}]}}}`)
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("5/1s")
+
+	load := ConfigFileLoader(path)
+	c, err := load()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	s, _ := c.Build()
+	burst := s.Limiter("api").Get("").(*ChainLimiter).Limiters()[0].(*BurstRateLimiter)
+
+	clock := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewConfigWatcher(s, load, time.Second, WithClock(clock))
+	errs := make(chan error, 1)
+	w.SetErrorHandler(func(err error) { errs <- err })
+	w.Start()
+	defer w.Stop()
+
+	write("7/1s")
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	if burst.maxCount != 7 {
+		t.Errorf("Expected 7, got %d", burst.maxCount)
+	}
+
+	write("0/1s")
+	clock.Advance(time.Second)
+	if err := <-errs; !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidRate, err)
+	}
+	if burst.maxCount != 7 {
+		t.Errorf("Expected 7, got %d", burst.maxCount)
+	}
+
+	w.Stop()
+	if clock.Sleepers() != 0 {
+		t.Errorf("Expected no pending timers after Stop, got %d", clock.Sleepers())
+	}
+}
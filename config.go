@@ -0,0 +1,519 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+var (
+	ErrUnknownStageType   = errors.New("Unknown limiter stage type.")
+	ErrUnknownBackOffType = errors.New("Unknown backoff type.")
+)
+
+/*
+Config describes a set of named limiters, and can be decoded from JSON or any format which maps onto the same structure.
+*/
+type Config struct {
+	Limiters map[string]LimiterConfig `json:"limiters"`
+}
+
+/*
+LimiterConfig describes a limiter composed of stages, outermost first, and optional per-key policies.
+
+When PerKey is true, every key gets its own instance of the limiter; otherwise all keys share one instance. Keys listed in Keys always get their own instance built from their own stages.
+*/
+type LimiterConfig struct {
+	Stages []StageConfig            `json:"stages"`
+	PerKey bool                     `json:"per_key,omitempty"`
+	Keys   map[string][]StageConfig `json:"keys,omitempty"`
+}
+
+/*
+StageConfig describes a single limiter within a composed limiter. Type selects the limiter and determines which parameters apply:
+
+- "burst-rate": rate (BurstRateLimiter)
+- "bucket-rate": rate, burst (BucketRateLimiter)
+- "interval": interval, recheck (IntervalLimiter)
+- "fixed-interval": interval (FixedIntervalLimiter)
+- "token": tokens (TokenChanLimiter)
+- "adjustable-token": tokens, max_tokens (AdjustableTokenChanLimiter)
+- "fail-backoff": backoff (FailBackOffLimiter)
+- "fail-rate": rate, backoff (FailRateLimiter)
+
+Rates are parsed with ParseRate, like "100/1s". Intervals are parsed with time.ParseDuration, like "250ms". A parameter which does not apply to the stage's type is rejected with a *ConfigError.
+*/
+type StageConfig struct {
+	Type      string         `json:"type"`
+	Rate      string         `json:"rate,omitempty"`
+	Burst     int            `json:"burst,omitempty"`
+	Interval  string         `json:"interval,omitempty"`
+	Recheck   string         `json:"recheck,omitempty"`
+	Tokens    uint           `json:"tokens,omitempty"`
+	MaxTokens uint           `json:"max_tokens,omitempty"`
+	BackOff   *BackOffConfig `json:"backoff,omitempty"`
+}
+
+/*
+BackOffConfig describes a backoff function. Type is one of "none", "full-jitter" or "half-jitter", and the jitter types use the Base and Max durations, like "10ms" and "5s".
+*/
+type BackOffConfig struct {
+	Type string `json:"type"`
+	Base string `json:"base,omitempty"`
+	Max  string `json:"max,omitempty"`
+}
+
+/*
+ConfigError describes an invalid value in a Config. Path locates the value, like "limiters.api.stages[1].rate", and Err describes the problem and can be matched with errors.Is.
+*/
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+/*
+ParseConfig decodes a Config from JSON and validates it, returning a *ConfigError for the first invalid value.
+*/
+func ParseConfig(data []byte) (c *Config, err error) {
+	c = &Config{}
+	if err = json.Unmarshal(data, c); err != nil {
+		c = nil
+		return
+	}
+	if err = c.Validate(); err != nil {
+		c = nil
+	}
+	return
+}
+
+/*
+Validate returns a *ConfigError for the first invalid value in the Config, in order of limiter name.
+*/
+func (c *Config) Validate() (err error) {
+	_, err = c.Build()
+	return
+}
+
+/*
+Build instantiates the limiters described by the Config, passing the provided options (like WithClock) to every limiter's constructor. A *ConfigError is returned for the first invalid value, in order of limiter name.
+*/
+func (c *Config) Build(opts ...Option) (s *LimiterSet, err error) {
+	s = &LimiterSet{
+		opts:     opts,
+		limiters: make(map[string]*configuredLimiter, len(c.Limiters)),
+	}
+	for _, name := range sortedKeys(c.Limiters) {
+		var cl *configuredLimiter
+		if cl, err = newConfiguredLimiter("limiters."+name, c.Limiters[name], opts); err != nil {
+			s = nil
+			return
+		}
+		s.limiters[name] = cl
+	}
+	return
+}
+
+// configuredLimiter holds the KeyedLimiter built from a LimiterConfig, along with the config and shared instance used to create limiters for new keys.
+type configuredLimiter struct {
+	mu     sync.Mutex
+	path   string
+	opts   []Option
+	config LimiterConfig
+	shared *ChainLimiter
+	keyed  *KeyedLimiter
+}
+
+func newConfiguredLimiter(path string, lc LimiterConfig, opts []Option) (cl *configuredLimiter, err error) {
+	cl = &configuredLimiter{
+		path:   path,
+		opts:   opts,
+		config: lc,
+	}
+	if cl.shared, err = buildStages(path, lc.Stages, opts); err != nil {
+		cl = nil
+		return
+	}
+	cl.keyed = newKeyedLimiter(cl.newKeyLimiter)
+	for _, key := range sortedKeys(lc.Keys) {
+		var l *ChainLimiter
+		if l, err = buildStages(path+".keys."+key, lc.Keys[key], opts); err != nil {
+			cl = nil
+			return
+		}
+		cl.keyed.set(key, l)
+	}
+	return
+}
+
+// newKeyLimiter returns the limiter for a key, which is retained by the KeyedLimiter unless it is the shared limiter.
+func (cl *configuredLimiter) newKeyLimiter(key string) (il InvocationLimiter, retain bool) {
+	cl.mu.Lock()
+	lc, shared := cl.config, cl.shared
+	cl.mu.Unlock()
+	if key == "" || !lc.PerKey && lc.Keys[key] == nil {
+		return shared, false
+	}
+	l, err := buildStages(cl.path, policyStages(lc, key), cl.opts)
+	if err != nil {
+		// stages were validated when the config was built or applied, so this is not expected
+		return shared, false
+	}
+	return l, true
+}
+
+// apply reconfigures the shared and per-key limiters in place where their stage types are unchanged, and replaces them otherwise. Limiters are reconfigured without holding mu, so that lookups of new keys are not delayed; the caller must not apply configs concurrently.
+func (cl *configuredLimiter) apply(lc LimiterConfig) {
+	cl.mu.Lock()
+	oldShared := cl.shared
+	cl.config = lc
+	cl.mu.Unlock()
+
+	shared := oldShared
+	if !reconfigureStages(cl.path, oldShared, lc.Stages) {
+		shared, _ = buildStages(cl.path, lc.Stages, cl.opts)
+		cl.mu.Lock()
+		cl.shared = shared
+		cl.mu.Unlock()
+	}
+
+	cl.keyed.Range(func(key string, il InvocationLimiter) bool {
+		_, listed := lc.Keys[key]
+		switch {
+		case key == "" || !lc.PerKey && !listed:
+			// the key now uses the shared limiter, which is not retained per key
+			cl.keyed.remove(key)
+		default:
+			if chain, ok := il.(*ChainLimiter); !ok || !reconfigureStages(cl.path, chain, policyStages(lc, key)) {
+				if l, retain := cl.newKeyLimiter(key); retain {
+					cl.keyed.set(key, l)
+				} else {
+					cl.keyed.remove(key)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// policyStages returns the stages which apply to the key.
+func policyStages(lc LimiterConfig, key string) []StageConfig {
+	if stages, ok := lc.Keys[key]; ok {
+		return stages
+	}
+	return lc.Stages
+}
+
+// reconfigureStages applies the stage configs to the chain's limiters if every limiter can be adjusted in place, and returns false without changes otherwise, so that the chain is rebuilt.
+func reconfigureStages(path string, chain *ChainLimiter, stages []StageConfig) bool {
+	ls := chain.Limiters()
+	if len(ls) != len(stages) {
+		return false
+	}
+	stageOpts := make([][]Option, len(stages))
+	for i, stage := range stages {
+		if !stage.matches(ls[i]) || !stage.adjustable(ls[i]) {
+			return false
+		}
+		var err error
+		if stageOpts[i], err = stage.reconfigureOptions(path); err != nil {
+			return false
+		}
+	}
+	for i, opts := range stageOpts {
+		if ls[i].(Reconfigurable).Reconfigure(opts...) != nil {
+			// the checks above should prevent this; a rebuilt chain replaces any partial changes
+			return false
+		}
+	}
+	return true
+}
+
+func buildStages(path string, stages []StageConfig, opts []Option) (l *ChainLimiter, err error) {
+	ls := make([]InvocationLimiter, len(stages))
+	for i, stage := range stages {
+		if ls[i], err = stage.build(path+".stages["+strconv.Itoa(i)+"]", opts); err != nil {
+			return
+		}
+	}
+	l = NewChainLimiter(ls...)
+	return
+}
+
+func (sc StageConfig) build(path string, opts []Option) (l InvocationLimiter, err error) {
+	stageOpts, err := sc.options(path)
+	if err != nil {
+		return
+	}
+	stageOpts = append(stageOpts, opts...)
+	switch sc.Type {
+	case "burst-rate":
+		l, err = BuildBurstRateLimiter(stageOpts...)
+	case "bucket-rate":
+		l, err = BuildBucketRateLimiter(stageOpts...)
+	case "interval":
+		l, err = BuildIntervalLimiter(stageOpts...)
+	case "fixed-interval":
+		l, err = BuildFixedIntervalLimiter(stageOpts...)
+	case "token":
+		l, err = BuildTokenChanLimiter(stageOpts...)
+	case "adjustable-token":
+		l, err = BuildAdjustableTokenChanLimiter(stageOpts...)
+	case "fail-backoff":
+		l, err = BuildFailBackOffLimiter(stageOpts...)
+	case "fail-rate":
+		l, err = BuildFailRateLimiter(stageOpts...)
+	default:
+		err = &ConfigError{path + ".type", fmt.Errorf("%w: %q", ErrUnknownStageType, sc.Type)}
+		return
+	}
+	var oe *OptionError
+	if errors.As(err, &oe) {
+		err = &ConfigError{path + "." + optionFields[oe.Option], oe.Err}
+	}
+	if err != nil {
+		l = nil
+	}
+	return
+}
+
+// matches returns true if the limiter is of the type built for the stage.
+func (sc StageConfig) matches(l InvocationLimiter) (ok bool) {
+	switch sc.Type {
+	case "burst-rate":
+		_, ok = l.(*BurstRateLimiter)
+	case "bucket-rate":
+		_, ok = l.(*BucketRateLimiter)
+	case "interval":
+		_, ok = l.(*IntervalLimiter)
+	case "fixed-interval":
+		_, ok = l.(*FixedIntervalLimiter)
+	case "token":
+		_, ok = l.(*TokenChanLimiter)
+	case "adjustable-token":
+		_, ok = l.(*AdjustableTokenChanLimiter)
+	case "fail-backoff":
+		_, ok = l.(*FailBackOffLimiter)
+	case "fail-rate":
+		_, ok = l.(*FailRateLimiter)
+	}
+	return
+}
+
+// adjustable returns true if the limiter, which matches the stage's type, can take the stage's parameters without being rebuilt.
+func (sc StageConfig) adjustable(l InvocationLimiter) bool {
+	switch tl := l.(type) {
+	case *TokenChanLimiter:
+		return sc.Tokens == uint(cap(tl.tokens))
+	case *AdjustableTokenChanLimiter:
+		return sc.MaxTokens <= uint(cap(tl.tokens)) && sc.Tokens <= uint(cap(tl.tokens))
+	}
+	return true
+}
+
+// reconfigureOptions converts the stage's parameters to options for Reconfigure, including defaults for parameters which are omitted.
+func (sc StageConfig) reconfigureOptions(path string) (opts []Option, err error) {
+	if opts, err = sc.options(path); err != nil {
+		return
+	}
+	switch sc.Type {
+	case "bucket-rate":
+		opts = append(opts, WithBurst(sc.Burst))
+	case "interval":
+		if sc.Recheck == "" {
+			d, _ := time.ParseDuration(sc.Interval)
+			opts = append(opts, WithRecheck(d*2))
+		}
+	case "adjustable-token":
+		opts = append(opts, WithTokens(sc.Tokens))
+	}
+	return
+}
+
+// optionFields maps constructor options to the StageConfig fields which set them.
+var optionFields = map[string]string{
+	"WithClock":     "clock",
+	"WithRate":      "rate",
+	"WithBurst":     "burst",
+	"WithTokens":    "tokens",
+	"WithMaxTokens": "max_tokens",
+	"WithInterval":  "interval",
+	"WithRecheck":   "recheck",
+	"WithBackOff":   "backoff",
+}
+
+// options converts the stage's parameters to constructor options.
+func (sc StageConfig) options(path string) (opts []Option, err error) {
+	if sc.Rate != "" {
+		var r Rate
+		if r, err = ParseRate(sc.Rate); err != nil {
+			err = &ConfigError{path + ".rate", err}
+			return
+		}
+		opts = append(opts, WithRate(r))
+	}
+	if sc.Burst != 0 {
+		opts = append(opts, WithBurst(sc.Burst))
+	}
+	if sc.Interval != "" {
+		var d time.Duration
+		if d, err = time.ParseDuration(sc.Interval); err != nil {
+			err = &ConfigError{path + ".interval", err}
+			return
+		}
+		opts = append(opts, WithInterval(d))
+	}
+	if sc.Recheck != "" {
+		var d time.Duration
+		if d, err = time.ParseDuration(sc.Recheck); err != nil {
+			err = &ConfigError{path + ".recheck", err}
+			return
+		}
+		opts = append(opts, WithRecheck(d))
+	}
+	if sc.Tokens != 0 {
+		opts = append(opts, WithTokens(sc.Tokens))
+	}
+	if sc.MaxTokens != 0 {
+		opts = append(opts, WithMaxTokens(sc.MaxTokens))
+	}
+	if sc.BackOff != nil {
+		var f func(uint) uint
+		if f, err = sc.BackOff.build(path+".backoff", sc.Type == "burst-rate"); err != nil {
+			return
+		}
+		opts = append(opts, WithBackOff(f))
+	}
+	return
+}
+
+// build creates the backoff function. Fail limiters interpret backoff values as milliseconds, and BurstRateLimiter as nanoseconds.
+func (bc BackOffConfig) build(path string, nanos bool) (f func(uint) uint, err error) {
+	if bc.Type == "none" {
+		f = backoff.None
+		return
+	}
+	unit := time.Millisecond
+	if nanos {
+		unit = time.Nanosecond
+	}
+	var base, max time.Duration
+	if base, err = parseOptionalDuration(bc.Base, unit); err != nil {
+		err = &ConfigError{path + ".base", err}
+		return
+	}
+	if max, err = time.ParseDuration(bc.Max); err != nil {
+		err = &ConfigError{path + ".max", err}
+		return
+	}
+	switch bc.Type {
+	case "full-jitter":
+		f = backoff.FullJitter(uint(base/unit), uint(max/unit))
+	case "half-jitter":
+		f = backoff.HalfJitter(uint(base/unit), uint(max/unit))
+	default:
+		err = &ConfigError{path + ".type", fmt.Errorf("%w: %q", ErrUnknownBackOffType, bc.Type)}
+	}
+	return
+}
+
+// parseOptionalDuration parses a duration, defaulting to the provided unit if the string is empty.
+func parseOptionalDuration(s string, unit time.Duration) (time.Duration, error) {
+	if s == "" {
+		return unit, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func sortedKeys[V any](m map[string]V) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+/*
+LimiterSet holds the limiters built from a Config, by name.
+*/
+type LimiterSet struct {
+	applyMu  sync.Mutex
+	mu       sync.Mutex
+	opts     []Option
+	limiters map[string]*configuredLimiter
+}
+
+/*
+Limiter returns the named limiter, or nil if the Config did not describe it. The returned KeyedLimiter applies per-key policies with InvokeKey, and the shared policy with Invoke.
+*/
+func (s *LimiterSet) Limiter(name string) *KeyedLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cl, ok := s.limiters[name]; ok {
+		return cl.keyed
+	}
+	return nil
+}
+
+/*
+Names returns the names of the limiters in the set, in sorted order.
+*/
+func (s *LimiterSet) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.limiters)
+}
+
+/*
+Apply updates the set's live limiters to match the provided Config.
+
+Stages whose type is unchanged are reconfigured in place, preserving their current usage and failure state; stages whose type or position changed are rebuilt. Limiters added to the Config are built, and limiters removed from it are dropped from the set. If the Config is invalid, a *ConfigError is returned and no changes are made.
+
+Concurrent calls are applied one at a time. Lookups with Limiter do not wait for limiters to be reconfigured.
+*/
+func (s *LimiterSet) Apply(c *Config) (err error) {
+	if _, err = c.Build(s.opts...); err != nil {
+		return
+	}
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	existing := make(map[string]*configuredLimiter, len(s.limiters))
+	for name, cl := range s.limiters {
+		if _, ok := c.Limiters[name]; !ok {
+			delete(s.limiters, name)
+			continue
+		}
+		existing[name] = cl
+	}
+	s.mu.Unlock()
+
+	for name, cl := range existing {
+		cl.apply(c.Limiters[name])
+	}
+	added := make(map[string]*configuredLimiter)
+	for name, lc := range c.Limiters {
+		if _, ok := existing[name]; !ok {
+			added[name], _ = newConfiguredLimiter("limiters."+name, lc, s.opts)
+		}
+	}
+	s.mu.Lock()
+	for name, cl := range added {
+		s.limiters[name] = cl
+	}
+	s.mu.Unlock()
+	return
+}
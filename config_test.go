@@ -0,0 +1,255 @@
+package limiter
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testConfig = `{
+	"limiters": {
+		"api": {
+			"stages": [
+				{"type": "burst-rate", "rate": "100/1s"},
+				{"type": "token", "tokens": 2},
+				{"type": "fail-backoff", "backoff": {"type": "half-jitter", "base": "1ms", "max": "1s"}}
+			],
+			"per_key": true,
+			"keys": {
+				"batch": [{"type": "interval", "interval": "10ms"}]
+			}
+		},
+		"shared": {
+			"stages": [{"type": "adjustable-token", "tokens": 1, "max_tokens": 4}]
+		}
+	}
+}`
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	s, err := c.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if names := s.Names(); len(names) != 2 || names[0] != "api" || names[1] != "shared" {
+		t.Fatalf("Expected [api shared], got %v", names)
+	}
+
+	api := s.Limiter("api")
+	chain, ok := api.Get("").(*ChainLimiter)
+	if !ok || len(chain.Limiters()) != 3 {
+		t.Fatalf("Expected chain of 3 limiters, got %#v", api.Get(""))
+	}
+	if _, ok := chain.Limiters()[1].(*TokenChanLimiter); !ok {
+		t.Errorf("Expected *TokenChanLimiter, got %T", chain.Limiters()[1])
+	}
+	if api.Get("a") == api.Get("b") {
+		t.Error("Expected per-key limiters to be distinct")
+	}
+	batch := api.Get("batch").(*ChainLimiter)
+	if _, ok := batch.Limiters()[0].(*IntervalLimiter); !ok {
+		t.Errorf("Expected *IntervalLimiter, got %T", batch.Limiters()[0])
+	}
+
+	shared := s.Limiter("shared")
+	if shared.Get("a") != shared.Get("b") {
+		t.Error("Expected keys to share a limiter")
+	}
+	if err := shared.InvokeKey("a", func() error { return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if s.Limiter("missing") != nil {
+		t.Error("Expected nil for missing limiter")
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	tests := []struct {
+		json string
+		path string
+		err  error
+	}{
+		{`{"limiters": {"a": {"stages": [{"type": "burst-rate", "rate": "0/1s"}]}}}`, "limiters.a.stages[0].rate", ErrInvalidRate},
+		{`{"limiters": {"a": {"stages": [{"type": "burst-rate"}]}}}`, "limiters.a.stages[0].rate", ErrMissingOption},
+		{`{"limiters": {"a": {"stages": [{"type": "token"}, {"type": "bogus"}]}}}`, "limiters.a.stages[0].tokens", ErrMissingOption},
+		{`{"limiters": {"a": {"stages": [{"type": "bogus"}]}}}`, "limiters.a.stages[0].type", ErrUnknownStageType},
+		{`{"limiters": {"a": {"stages": [{"type": "adjustable-token", "tokens": 5, "max_tokens": 2}]}}}`, "limiters.a.stages[0].tokens", ErrTokenCountExceedsMax},
+		{`{"limiters": {"a": {"stages": [{"type": "fail-backoff", "backoff": {"type": "bogus", "max": "1s"}}]}}}`, "limiters.a.stages[0].backoff.type", ErrUnknownBackOffType},
+		{`{"limiters": {"a": {"stages": [], "keys": {"k": [{"type": "interval", "interval": "0s"}]}}}}`, "limiters.a.keys.k.stages[0].interval", ErrInvalidInterval},
+	}
+	for _, test := range tests {
+		_, err := ParseConfig([]byte(test.json))
+		var ce *ConfigError
+		if !errors.As(err, &ce) {
+			t.Errorf("Expected *ConfigError, got '%v'", err)
+			continue
+		}
+		if ce.Path != test.path {
+			t.Errorf("Expected path %s, got %s", test.path, ce.Path)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("Expected '%v', got '%v'", test.err, err)
+		}
+	}
+}
+
+func TestLimiterSet_Apply(t *testing.T) {
+	c, _ := ParseConfig([]byte(testConfig))
+	s, err := c.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	api := s.Limiter("api")
+	a := api.Get("a").(*ChainLimiter)
+	burst := a.Limiters()[0].(*BurstRateLimiter)
+	burst.CheckWait()
+	batch := api.Get("batch")
+
+	updated, err := ParseConfig([]byte(`{
+		"limiters": {
+			"api": {
+				"stages": [
+					{"type": "burst-rate", "rate": "10/1s"},
+					{"type": "token", "tokens": 2},
+					{"type": "fail-backoff", "backoff": {"type": "none"}}
+				],
+				"per_key": true,
+				"keys": {
+					"batch": [{"type": "fixed-interval", "interval": "10ms"}]
+				}
+			},
+			"new": {
+				"stages": [{"type": "token", "tokens": 1}]
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err := s.Apply(updated); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+
+	if s.Limiter("api") != api {
+		t.Error("Expected limiter to be preserved")
+	}
+	if api.Get("a") != a || a.Limiters()[0] != burst {
+		t.Error("Expected stages to be reconfigured in place")
+	}
+	if burst.maxCount != 10 || burst.count != 1 {
+		t.Errorf("Expected max 10 with usage 1, got max %d with usage %d", burst.maxCount, burst.count)
+	}
+	if api.Get("batch") == batch {
+		t.Error("Expected stage with changed type to be rebuilt")
+	}
+	if _, ok := api.Get("batch").(*ChainLimiter).Limiters()[0].(*FixedIntervalLimiter); !ok {
+		t.Errorf("Expected *FixedIntervalLimiter, got %T", api.Get("batch").(*ChainLimiter).Limiters()[0])
+	}
+	if names := s.Names(); len(names) != 2 || names[0] != "api" || names[1] != "new" {
+		t.Errorf("Expected [api new], got %v", names)
+	}
+
+	invalid, _ := ParseConfig([]byte(`{"limiters": {}}`))
+	invalid.Limiters["api"] = LimiterConfig{Stages: []StageConfig{{Type: "burst-rate", Rate: "0/1s"}}}
+	var ce *ConfigError
+	if err := s.Apply(invalid); !errors.As(err, &ce) {
+		t.Fatalf("Expected *ConfigError, got '%v'", err)
+	}
+	if burst.maxCount != 10 || len(s.Names()) != 2 {
+		t.Error("Expected invalid config to make no changes")
+	}
+}
+
+func TestLimiterSet_ApplyRebuildsFixedStages(t *testing.T) {
+	c, _ := ParseConfig([]byte(`{"limiters": {"db": {"stages": [
+		{"type": "token", "tokens": 4},
+		{"type": "adjustable-token", "tokens": 1, "max_tokens": 2}
+	]}}}`))
+	s, err := c.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	before := s.Limiter("db").Get("").(*ChainLimiter)
+
+	updated, _ := ParseConfig([]byte(`{"limiters": {"db": {"stages": [
+		{"type": "token", "tokens": 8},
+		{"type": "adjustable-token", "tokens": 1, "max_tokens": 4}
+	]}}}`))
+	if err := s.Apply(updated); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+
+	after := s.Limiter("db").Get("").(*ChainLimiter)
+	if after == before {
+		t.Fatal("Expected stages which cannot be adjusted in place to be rebuilt")
+	}
+	if actual := cap(after.Limiters()[0].(*TokenChanLimiter).tokens); actual != 8 {
+		t.Errorf("Expected '%d' tokens, got '%d'", 8, actual)
+	}
+	if actual := after.Limiters()[1].(*AdjustableTokenChanLimiter).maxTokenCount; actual != 4 {
+		t.Errorf("Expected max '%d' tokens, got '%d'", 4, actual)
+	}
+}
+
+func TestLimiterSet_ApplyWhileTokensHeld(t *testing.T) {
+	c, _ := ParseConfig([]byte(`{"limiters": {"db": {"stages": [
+		{"type": "adjustable-token", "tokens": 2, "max_tokens": 2}
+	]}}}`))
+	s, err := c.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	tl := s.Limiter("db").Get("").(*ChainLimiter).Limiters()[0].(*AdjustableTokenChanLimiter)
+	token := tl.AcquireToken()
+	defer tl.ReleaseToken(token)
+
+	// lowering the token count to 0 retires the held token later instead of waiting for it
+	updated, _ := ParseConfig([]byte(`{"limiters": {"db": {"stages": [
+		{"type": "adjustable-token", "tokens": 0, "max_tokens": 2}
+	]}}}`))
+	done := make(chan error)
+	go func() {
+		done <- s.Apply(updated)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Apply to return while a token is held")
+	}
+	if actual := tl.PendingShrink(); actual != 1 {
+		t.Errorf("Expected '%d' pending, got '%d'", 1, actual)
+	}
+}
+
+func TestLimiterSet_SharedKeysNotRetained(t *testing.T) {
+	c, _ := ParseConfig([]byte(`{"limiters": {"api": {
+		"stages": [{"type": "token", "tokens": 1}],
+		"keys": {"batch": [{"type": "token", "tokens": 2}]}
+	}}}`))
+	s, err := c.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	api := s.Limiter("api")
+	shared := api.Get("")
+	for i := 0; i < 100; i += 1 {
+		if api.Get(strconv.Itoa(i)) != shared {
+			t.Fatal("Expected unlisted keys to use the shared limiter")
+		}
+	}
+	keys := 0
+	api.Range(func(string, InvocationLimiter) bool {
+		keys += 1
+		return true
+	})
+	if keys != 1 {
+		t.Errorf("Expected only the listed key to be retained, got '%d' keys", keys)
+	}
+}
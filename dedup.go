@@ -0,0 +1,120 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrInvocationAborted = errors.New("Shared invocation exited without returning.")
+
+/*
+DedupLimiter wraps an InvocationLimiter, collapsing concurrent invocations for the same key into a single invocation through the wrapped limiter. Callers which arrive while an invocation for their key is in progress wait for it and share its result, so a burst of identical requests consumes one token or rate unit and is reported to fail limiters once.
+
+It satisfies the InvocationLimiter interface; Invoke has no key and is passed to the wrapped limiter without deduplication.
+*/
+type DedupLimiter struct {
+	mu          sync.Mutex
+	limiter     InvocationLimiter
+	calls       map[string]*dedupCall
+	panicPolicy PanicPolicy
+}
+
+type dedupCall struct {
+	done chan struct{}
+	v    any
+	err  error
+}
+
+/*
+NewDedupLimiter instantiates a new DedupLimiter which invokes functions through the provided limiter.
+
+WithPanicPolicy sets what the caller whose function panics does after the panic has been shared with the other callers, which receive a *PanicError.
+*/
+func NewDedupLimiter(l InvocationLimiter, opts ...Option) (d *DedupLimiter) {
+	d = &DedupLimiter{
+		limiter:     l,
+		calls:       make(map[string]*dedupCall),
+		panicPolicy: newOptions(opts).panicPolicy,
+	}
+	return
+}
+
+/*
+InvokeKey invokes the passed function through the wrapped limiter, unless an invocation for the same key is already in progress, in which case it waits for that invocation and returns its error.
+*/
+func (d *DedupLimiter) InvokeKey(key string, f func() error) (err error) {
+	_, _, err = d.do(context.Background(), key, func() (any, error) {
+		return nil, f()
+	})
+	return
+}
+
+/*
+Invoke enforces the wrapped limiter's limits around the invocation of the passed function, without deduplication. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (d *DedupLimiter) Invoke(f func() error) error {
+	return d.limiter.Invoke(f)
+}
+
+/*
+InFlight returns the number of keys with an invocation in progress.
+*/
+func (d *DedupLimiter) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.calls)
+}
+
+/*
+DoDedup invokes the passed function through the DedupLimiter and returns its result, unless an invocation for the same key is already in progress, in which case it waits for that invocation and returns its result with shared set to true.
+
+The function is called with the context of the caller which started the invocation. Waiting callers stop waiting when their own context is done, and return its error. Every caller of a key must use the same result type.
+*/
+func DoDedup[T any](ctx context.Context, d *DedupLimiter, key string, f func(ctx context.Context) (T, error)) (v T, shared bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	result, shared, err := d.do(ctx, key, func() (any, error) {
+		return f(ctx)
+	})
+	v, _ = result.(T)
+	return
+}
+
+func (d *DedupLimiter) do(ctx context.Context, key string, f func() (any, error)) (v any, shared bool, err error) {
+	d.mu.Lock()
+	if c, ok := d.calls[key]; ok {
+		d.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.v, true, c.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	c := &dedupCall{done: make(chan struct{}), err: ErrInvocationAborted}
+	d.calls[key] = c
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.calls, key)
+		d.mu.Unlock()
+		close(c.done)
+	}()
+	// panics are converted so that waiting callers receive them, then applied to this caller by its policy
+	panicked := true
+	c.err = ConvertPanic.invoke(func() (fErr error) {
+		fErr = d.limiter.Invoke(func() (err error) {
+			c.v, err = f()
+			return
+		})
+		panicked = false
+		return
+	}, nil)
+	if panicked && d.panicPolicy == RePanic {
+		panic(c.err.(*PanicError).Value)
+	}
+	return c.v, false, c.err
+}
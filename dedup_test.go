@@ -0,0 +1,189 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestDedupLimiter_InvokeKey(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	d := NewDedupLimiter(fl)
+	release := make(chan struct{})
+	calls := 0
+	expected := errors.New("error")
+
+	var wg, calling sync.WaitGroup
+	errs := make([]error, 5)
+	calling.Add(len(errs) - 1)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i > 0 {
+				calling.Done()
+			}
+			errs[i] = d.InvokeKey("lookup", func() error {
+				calls += 1
+				<-release
+				return expected
+			})
+		}(i)
+		if i == 0 {
+			waitForDedupInFlight(d, 1)
+		}
+	}
+	waitForDedupCallers(&calling)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected '%d' call, got '%d'", 1, calls)
+	}
+	for _, err := range errs {
+		if err != expected {
+			t.Errorf("Expected '%v', got '%v'", expected, err)
+		}
+	}
+	if fl.failCount != 1 {
+		t.Errorf("Expected fail count '%d', got '%d'", 1, fl.failCount)
+	}
+	if actual := d.InFlight(); actual != 0 {
+		t.Errorf("Expected '%d' in flight, got '%d'", 0, actual)
+	}
+}
+
+func TestDedupLimiter_DistinctKeys(t *testing.T) {
+	d := NewDedupLimiter(NewTokenChanLimiter(2))
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			d.InvokeKey(key, func() error {
+				<-release
+				return nil
+			})
+		}(key)
+	}
+	waitForDedupInFlight(d, 2)
+	close(release)
+	wg.Wait()
+}
+
+func TestDoDedup(t *testing.T) {
+	d := NewDedupLimiter(NewTokenChanLimiter(1))
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leader := make(chan bool)
+	go func() {
+		v, shared, _ := DoDedup(context.Background(), d, "k", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		leader <- shared && v == 42
+	}()
+	<-started
+
+	var calling sync.WaitGroup
+	calling.Add(1)
+	follower := make(chan int)
+	go func() {
+		calling.Done()
+		v, shared, err := DoDedup(context.Background(), d, "k", func(ctx context.Context) (int, error) {
+			t.Error("Expected shared invocation, got a second call")
+			return 0, nil
+		})
+		if !shared || err != nil {
+			t.Errorf("Expected shared result without error, got shared '%t' and '%v'", shared, err)
+		}
+		follower <- v
+	}()
+	waitForDedupCallers(&calling)
+	close(release)
+
+	if <-leader {
+		t.Error("Expected leader's result not to be shared")
+	}
+	if v := <-follower; v != 42 {
+		t.Errorf("Expected '%d', got '%d'", 42, v)
+	}
+}
+
+func TestDoDedup_WaiterCancel(t *testing.T) {
+	d := NewDedupLimiter(NewTokenChanLimiter(1))
+	release := make(chan struct{})
+	defer close(release)
+	go d.InvokeKey("k", func() error {
+		<-release
+		return nil
+	})
+	waitForDedupInFlight(d, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, _, err := DoDedup(ctx, d, "k", func(ctx context.Context) (int, error) { return 0, nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+}
+
+func TestDedupLimiter_Panic(t *testing.T) {
+	tl := NewTokenChanLimiter(1)
+	d := NewDedupLimiter(tl)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	recovered := make(chan any)
+	go func() {
+		defer func() {
+			recovered <- recover()
+		}()
+		d.InvokeKey("k", func() error {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	var calling sync.WaitGroup
+	calling.Add(1)
+	result := make(chan error)
+	go func() {
+		calling.Done()
+		result <- d.InvokeKey("k", func() error { return nil })
+	}()
+	waitForDedupCallers(&calling)
+	close(release)
+
+	if v := <-recovered; v != "boom" {
+		t.Errorf("Expected panic value 'boom', got '%v'", v)
+	}
+	var pe *PanicError
+	if err := <-result; !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("Expected *PanicError with 'boom', got '%v'", err)
+	}
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released, got none")
+	}
+}
+
+func waitForDedupInFlight(d *DedupLimiter, n int) {
+	for d.InFlight() != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForDedupCallers waits until the callers are about to call, then gives them time to join the invocation in progress, which is not observable.
+func waitForDedupCallers(calling *sync.WaitGroup) {
+	calling.Wait()
+	time.Sleep(10 * time.Millisecond)
+}
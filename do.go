@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/*
+MaxDoConcurrency is the most goroutines which DoAll and Map start at a time, which bounds the concurrency the limiter may permit.
+*/
+const MaxDoConcurrency = 64
+
+/*
+Do invokes the passed function through the InvocationLimiter and returns its result, so that results need not be captured in closures. It works with every limiter and combinator which satisfies the InvocationLimiter interface.
+
+If the context is done by the time the limiter permits the invocation, the function is not called and the context's error is returned. The function's error is passed through the limiter, so fail limiters observe it, except for the context's error once it is done: a canceled invocation is reported to the limiter as a success, so that canceling a batch of calls does not count as failures.
+*/
+func Do[T any](ctx context.Context, l InvocationLimiter, f func(ctx context.Context) (T, error)) (v T, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	var canceled error
+	err = l.Invoke(func() (fErr error) {
+		if canceled = ctx.Err(); canceled != nil {
+			return
+		}
+		v, fErr = f(ctx)
+		if ctxErr := ctx.Err(); fErr != nil && ctxErr != nil && errors.Is(fErr, ctxErr) {
+			canceled, fErr = fErr, nil
+		}
+		return
+	})
+	if err == nil {
+		err = canceled
+	}
+	return
+}
+
+/*
+DoAll invokes each of the passed functions concurrently through the InvocationLimiter, and returns their results and errors in the order of the functions.
+
+Concurrency is governed by the limiter, such as a TokenChanLimiter, up to MaxDoConcurrency goroutines which take functions in order. Once the context is done, functions which have not yet been passed to the limiter are skipped, and functions which the limiter permits afterwards are not called; both have the context's error in their position. The limiter's wait cannot be interrupted, so DoAll returns when the waits in progress end.
+*/
+func DoAll[T any](ctx context.Context, l InvocationLimiter, fs ...func(ctx context.Context) (T, error)) (results []T, errs []error) {
+	results = make([]T, len(fs))
+	errs = make([]error, len(fs))
+	workers := len(fs)
+	if workers > MaxDoConcurrency {
+		workers = MaxDoConcurrency
+	}
+	var next int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w += 1 {
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next += 1
+				mu.Unlock()
+				if i >= len(fs) {
+					return
+				}
+				results[i], errs[i] = Do(ctx, l, fs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+/*
+Map applies the passed function to each of the inputs concurrently through the InvocationLimiter, and returns the results and errors in the order of the inputs.
+
+Like DoAll, concurrency is governed by the limiter up to MaxDoConcurrency goroutines, and inputs which have not been processed when the context is done are skipped, with the context's error in their position.
+*/
+func Map[In, Out any](ctx context.Context, l InvocationLimiter, inputs []In, f func(ctx context.Context, in In) (Out, error)) (results []Out, errs []error) {
+	fs := make([]func(context.Context) (Out, error), len(inputs))
+	for i, in := range inputs {
+		in := in
+		fs[i] = func(ctx context.Context) (Out, error) {
+			return f(ctx, in)
+		}
+	}
+	return DoAll(ctx, l, fs...)
+}
@@ -0,0 +1,148 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestDo(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	l := NewChainLimiter(NewTokenChanLimiter(1), fl)
+
+	n, err := Do(context.Background(), l, func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || n != 42 {
+		t.Errorf("Expected 42, got %d (%v)", n, err)
+	}
+
+	if _, err := Do(context.Background(), l, func(ctx context.Context) (string, error) {
+		return "", errors.New("error")
+	}); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if fl.failCount != 1 {
+		t.Errorf("Expected failure to be reported to the limiter, got %d", fl.failCount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if _, err := Do(ctx, l, func(ctx context.Context) (int, error) {
+		called = true
+		return 0, nil
+	}); !errors.Is(err, context.Canceled) || called {
+		t.Errorf("Expected canceled without invocation, got '%v'", err)
+	}
+}
+
+func TestDo_CanceledIsNotFailure(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the function observes the cancellation and returns the context's error
+	if _, err := Do(ctx, fl, func(ctx context.Context) (int, error) {
+		cancel()
+		return 0, ctx.Err()
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+	if fl.failCount != 0 {
+		t.Errorf("Expected fail count '%d', got '%d'", 0, fl.failCount)
+	}
+
+	// functions skipped after the limiter permits them are not failures either
+	_, errs := DoAll(ctx, fl, func(ctx context.Context) (int, error) { return 0, nil })
+	if !errors.Is(errs[0], context.Canceled) || fl.failCount != 0 {
+		t.Errorf("Expected '%v' with no failures, got '%v' with fail count '%d'", context.Canceled, errs[0], fl.failCount)
+	}
+}
+
+func TestMap(t *testing.T) {
+	var running, maxRunning int32
+	l := NewTokenChanLimiter(2)
+	inputs := []string{"1", "2", "x", "4", "5"}
+
+	results, errs := Map(context.Background(), l, inputs, func(ctx context.Context, in string) (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		return strconv.Atoi(in)
+	})
+	for i, expected := range []int{1, 2, 0, 4, 5} {
+		if results[i] != expected {
+			t.Errorf("%d: expected %d, got %d", i, expected, results[i])
+		}
+		if (errs[i] != nil) != (i == 2) {
+			t.Errorf("%d: unexpected error state '%v'", i, errs[i])
+		}
+	}
+	if maxRunning > 2 {
+		t.Errorf("Expected at most 2 concurrent invocations, got %d", maxRunning)
+	}
+}
+
+func TestDoAll_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// whichever function is invoked first cancels the context, so the other is skipped
+	f := func(n int) func(context.Context) (int, error) {
+		return func(ctx context.Context) (int, error) {
+			cancel()
+			return n, nil
+		}
+	}
+	results, errs := DoAll(ctx, NewTokenChanLimiter(1), f(1), f(2))
+	canceled := 0
+	for i := range results {
+		if errors.Is(errs[i], context.Canceled) {
+			canceled += 1
+		} else if results[i] != i+1 {
+			t.Errorf("%d: expected %d, got %d", i, i+1, results[i])
+		}
+	}
+	if canceled != 1 {
+		t.Errorf("Expected 1 canceled invocation, got %d", canceled)
+	}
+}
+
+func TestDoAll_BoundsGoroutines(t *testing.T) {
+	var running, maxRunning int32
+	fs := make([]func(context.Context) (int, error), 4*MaxDoConcurrency)
+	for i := range fs {
+		n := i
+		fs[i] = func(ctx context.Context) (int, error) {
+			r := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+					break
+				}
+			}
+			runtime.Gosched()
+			return n, nil
+		}
+	}
+	// the limiter permits every invocation, so only the goroutine bound limits concurrency
+	results, errs := DoAll(context.Background(), NewTokenChanLimiter(uint(len(fs))), fs...)
+	for i := range fs {
+		if results[i] != i || errs[i] != nil {
+			t.Errorf("%d: expected %d, got %d (%v)", i, i, results[i], errs[i])
+		}
+	}
+	if maxRunning > MaxDoConcurrency {
+		t.Errorf("Expected at most %d concurrent invocations, got %d", MaxDoConcurrency, maxRunning)
+	}
+}
@@ -0,0 +1,303 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Edge selects when an EventLimiter emits events relative to a burst: on the leading edge, as the first event arrives, or on the trailing edge, with the latest event once the burst has passed. Edges can be combined with |.
+*/
+type Edge int
+
+const (
+	LeadingEdge Edge = 1 << iota
+	TrailingEdge
+)
+
+type eventMode int
+
+const (
+	throttleEvents eventMode = iota
+	debounceEvents
+	coalesceEvents
+)
+
+/*
+EventLimiter limits a stream of events, such as change notifications or configuration reloads, by coalescing them: only the latest value is kept, and it is emitted according to the limiter's throttle, debounce or coalescing policy.
+
+Events are passed to Trigger, which never blocks. Emissions are made by calling the limiter's function from a single goroutine, so they are never concurrent; if the function is still running when another event is emitted, it is next called with only the latest one. The function may call Trigger, but not Stop.
+*/
+type EventLimiter[T any] struct {
+	mu          sync.Mutex
+	clock       Clock
+	mode        eventMode
+	interval    time.Duration
+	maxWait     time.Duration
+	edges       Edge
+	emit        func(T)
+	held        T
+	holding     bool
+	timer       Timer
+	cancelTimer chan struct{}
+	burstStart  time.Time
+	out         T
+	outReady    bool
+	stopped     bool
+	notify      chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+/*
+NewThrottle instantiates a new EventLimiter which calls f at most once per interval.
+
+WithEdges selects the edges, which default to both: the first event is emitted immediately, and the latest event received during each interval is emitted at its end. With only the leading edge, events received during the interval are dropped; with only the trailing edge, the first event is held until the interval ends.
+*/
+func NewThrottle[T any](interval time.Duration, f func(T), opts ...Option) *EventLimiter[T] {
+	return newEventLimiter(throttleEvents, interval, f, LeadingEdge|TrailingEdge, opts)
+}
+
+/*
+NewDebounce instantiates a new EventLimiter which calls f with the latest event once events have stopped arriving for the wait duration.
+
+WithEdges selects the edges, which default to the trailing edge; with the leading edge, the first event of each burst is emitted immediately. WithMaxWait limits how long a continuous burst can delay emission, after which the latest event is emitted and a new burst begins.
+*/
+func NewDebounce[T any](wait time.Duration, f func(T), opts ...Option) *EventLimiter[T] {
+	return newEventLimiter(debounceEvents, wait, f, TrailingEdge, opts)
+}
+
+/*
+NewCoalescer instantiates a new EventLimiter which calls f with the latest event whenever f is not already running, so a slow consumer skips intermediate values instead of falling behind.
+*/
+func NewCoalescer[T any](f func(T), opts ...Option) *EventLimiter[T] {
+	return newEventLimiter(coalesceEvents, 0, f, TrailingEdge, opts)
+}
+
+func newEventLimiter[T any](mode eventMode, interval time.Duration, f func(T), edges Edge, opts []Option) (l *EventLimiter[T]) {
+	o := newOptions(opts)
+	if o.edges != 0 {
+		edges = o.edges
+	}
+	l = &EventLimiter[T]{
+		clock:    o.clock,
+		mode:     mode,
+		interval: interval,
+		maxWait:  o.maxWait,
+		edges:    edges,
+		emit:     f,
+		notify:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return
+}
+
+/*
+Trigger passes an event to the limiter. It never blocks, and has no effect after Stop.
+*/
+func (l *EventLimiter[T]) Trigger(v T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return
+	}
+	switch l.mode {
+	case coalesceEvents:
+		l.send(v)
+	case throttleEvents:
+		if l.timer != nil {
+			if l.edges&TrailingEdge != 0 {
+				l.hold(v)
+			}
+			return
+		}
+		if l.edges&LeadingEdge != 0 {
+			l.send(v)
+		} else {
+			l.hold(v)
+		}
+		l.startTimer(l.interval)
+	case debounceEvents:
+		now := l.clock.Now()
+		if l.timer == nil {
+			l.burstStart = now
+			if l.edges&LeadingEdge != 0 {
+				l.send(v)
+			} else {
+				l.hold(v)
+			}
+		} else {
+			l.stopTimer()
+			l.hold(v)
+		}
+		wait := l.interval
+		if l.maxWait > 0 {
+			if remaining := l.burstStart.Add(l.maxWait).Sub(now); remaining < wait {
+				wait = remaining
+			}
+		}
+		l.startTimer(wait)
+	}
+}
+
+/*
+Stop emits any event held for the trailing edge, then stops the limiter and waits for the last emission to complete. Events triggered after Stop are ignored.
+*/
+func (l *EventLimiter[T]) Stop() {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		<-l.done
+		return
+	}
+	l.stopped = true
+	if l.timer != nil {
+		l.stopTimer()
+	}
+	if l.holding && l.edges&TrailingEdge != 0 {
+		l.send(l.held)
+	}
+	l.mu.Unlock()
+	close(l.stop)
+	<-l.done
+}
+
+// hold, send, startTimer and stopTimer must be called with mu held.
+func (l *EventLimiter[T]) hold(v T) {
+	l.held, l.holding = v, true
+}
+
+// send passes an event to the emitting goroutine, replacing one it has not yet taken.
+func (l *EventLimiter[T]) send(v T) {
+	l.out, l.outReady = v, true
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (l *EventLimiter[T]) startTimer(d time.Duration) {
+	t, cancel := l.clock.NewTimer(d), make(chan struct{})
+	l.timer, l.cancelTimer = t, cancel
+	go func() {
+		select {
+		case <-t.C():
+			l.fire(t)
+		case <-cancel:
+		}
+	}()
+}
+
+func (l *EventLimiter[T]) stopTimer() {
+	l.timer.Stop()
+	close(l.cancelTimer)
+	l.timer, l.cancelTimer = nil, nil
+}
+
+// fire ends the interval or quiet period of the provided timer, unless it has since been replaced or stopped.
+func (l *EventLimiter[T]) fire(t Timer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != t {
+		return
+	}
+	l.timer, l.cancelTimer = nil, nil
+	emit := l.holding && l.edges&TrailingEdge != 0
+	if emit {
+		l.send(l.held)
+	}
+	var zero T
+	l.held, l.holding = zero, false
+	if emit && l.mode == throttleEvents {
+		// the trailing emission begins a new interval
+		l.startTimer(l.interval)
+	}
+}
+
+func (l *EventLimiter[T]) run() {
+	defer close(l.done)
+	for {
+		select {
+		case <-l.notify:
+			l.emitOut()
+		case <-l.stop:
+			l.emitOut()
+			return
+		}
+	}
+}
+
+func (l *EventLimiter[T]) emitOut() {
+	l.mu.Lock()
+	v, ok := l.out, l.outReady
+	var zero T
+	l.out, l.outReady = zero, false
+	l.mu.Unlock()
+	if ok {
+		l.emit(v)
+	}
+}
+
+/*
+Throttle returns a channel which receives the events of the input channel limited as by NewThrottle. The returned channel is unbuffered, and is closed after the input channel is closed and any held event has been received.
+
+If the context is done first, held events are discarded, the returned channel is closed, and the remaining input is drained and discarded in the background so that the producer is not blocked. Consumers which stop receiving before the returned channel is closed must cancel the context.
+*/
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration, opts ...Option) <-chan T {
+	out := make(chan T)
+	return pipeEvents(ctx, in, out, NewThrottle(interval, sendEvent(ctx, out), opts...))
+}
+
+/*
+Debounce returns a channel which receives the events of the input channel limited as by NewDebounce. The returned channel is unbuffered, and is closed after the input channel is closed and any held event has been received.
+
+The context ends the stage as it does for Throttle.
+*/
+func Debounce[T any](ctx context.Context, in <-chan T, wait time.Duration, opts ...Option) <-chan T {
+	out := make(chan T)
+	return pipeEvents(ctx, in, out, NewDebounce(wait, sendEvent(ctx, out), opts...))
+}
+
+/*
+Coalesce returns a channel which receives the latest event of the input channel whenever its receiver is ready, skipping intermediate events. It is closed after the input channel is closed and the latest event has been received.
+
+The context ends the stage as it does for Throttle.
+*/
+func Coalesce[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	return pipeEvents(ctx, in, out, NewCoalescer(sendEvent(ctx, out)))
+}
+
+// sendEvent returns an emit function which sends events to out, giving up once the context is done.
+func sendEvent[T any](ctx context.Context, out chan<- T) func(T) {
+	return func(v T) {
+		select {
+		case out <- v:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func pipeEvents[T any](ctx context.Context, in <-chan T, out chan T, l *EventLimiter[T]) <-chan T {
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				l.Stop()
+				go drain(in)
+				return
+			case v, ok := <-in:
+				if !ok {
+					l.Stop()
+					return
+				}
+				l.Trigger(v)
+			}
+		}
+	}()
+	return out
+}
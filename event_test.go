@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func expectEvent(t *testing.T, got <-chan int, expected int) {
+	t.Helper()
+	select {
+	case v := <-got:
+		if v != expected {
+			t.Errorf("Expected event '%d', got '%d'", expected, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected event '%d', got none", expected)
+	}
+}
+
+func expectNoEvent(t *testing.T, got <-chan int) {
+	t.Helper()
+	select {
+	case v := <-got:
+		t.Errorf("Expected no event, got '%d'", v)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// waitForEventTimer waits until the limiter's timer has been handled, or replaced by a new one.
+func waitForEventTimer[T any](l *EventLimiter[T], active bool) {
+	for {
+		l.mu.Lock()
+		current := l.timer != nil
+		l.mu.Unlock()
+		if current == active {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewThrottle(time.Second, func(v int) { got <- v }, WithClock(c))
+	defer l.Stop()
+
+	l.Trigger(1)
+	expectEvent(t, got, 1)
+	l.Trigger(2)
+	l.Trigger(3)
+	expectNoEvent(t, got)
+
+	c.Advance(time.Second)
+	expectEvent(t, got, 3)
+
+	// the trailing emission starts a new interval, which ends quietly
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	waitForEventTimer(l, false)
+
+	l.Trigger(4)
+	expectEvent(t, got, 4)
+}
+
+func TestThrottle_LeadingOnly(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewThrottle(time.Second, func(v int) { got <- v }, WithClock(c), WithEdges(LeadingEdge))
+
+	l.Trigger(1)
+	expectEvent(t, got, 1)
+	l.Trigger(2)
+	c.Advance(time.Second)
+	waitForEventTimer(l, false)
+	expectNoEvent(t, got)
+
+	l.Trigger(3)
+	expectEvent(t, got, 3)
+	l.Trigger(4)
+	l.Stop()
+	expectNoEvent(t, got)
+}
+
+func TestDebounce(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewDebounce(time.Second, func(v int) { got <- v }, WithClock(c))
+	defer l.Stop()
+
+	l.Trigger(1)
+	c.Advance(900 * time.Millisecond)
+	l.Trigger(2)
+	c.Advance(900 * time.Millisecond)
+	expectNoEvent(t, got)
+
+	c.Advance(100 * time.Millisecond)
+	expectEvent(t, got, 2)
+}
+
+func TestDebounce_MaxWait(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewDebounce(time.Second, func(v int) { got <- v }, WithClock(c), WithMaxWait(2*time.Second))
+	defer l.Stop()
+
+	for i := 1; i <= 4; i += 1 {
+		if i > 1 {
+			c.Advance(600 * time.Millisecond)
+		}
+		l.Trigger(i)
+	}
+	// the fourth event arrived at 1.8s, so the burst is cut off at 2s
+	expectNoEvent(t, got)
+	c.Advance(200 * time.Millisecond)
+	expectEvent(t, got, 4)
+}
+
+func TestDebounce_Leading(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewDebounce(time.Second, func(v int) { got <- v }, WithClock(c), WithEdges(LeadingEdge|TrailingEdge))
+	defer l.Stop()
+
+	l.Trigger(1)
+	expectEvent(t, got, 1)
+	l.Trigger(2)
+	c.Advance(time.Second)
+	expectEvent(t, got, 2)
+}
+
+func TestEventLimiter_StopFlushes(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	got := make(chan int, 10)
+	l := NewDebounce(time.Second, func(v int) { got <- v }, WithClock(c))
+
+	l.Trigger(1)
+	l.Stop()
+	expectEvent(t, got, 1)
+	if actual := c.Sleepers(); actual != 0 {
+		t.Errorf("Expected timer to be stopped, got '%d' sleepers", actual)
+	}
+
+	l.Trigger(2)
+	expectNoEvent(t, got)
+}
+
+func TestCoalescer(t *testing.T) {
+	started := make(chan int)
+	release := make(chan struct{})
+	l := NewCoalescer(func(v int) {
+		started <- v
+		<-release
+	})
+
+	l.Trigger(1)
+	if v := <-started; v != 1 {
+		t.Errorf("Expected event '%d', got '%d'", 1, v)
+	}
+	// the consumer is busy, so only the latest of these is delivered
+	l.Trigger(2)
+	l.Trigger(3)
+	release <- struct{}{}
+	if v := <-started; v != 3 {
+		t.Errorf("Expected event '%d', got '%d'", 3, v)
+	}
+	close(release)
+	l.Stop()
+}
+
+func TestDebounceChannel(t *testing.T) {
+	in := make(chan int)
+	out := Debounce(context.Background(), in, time.Hour)
+
+	in <- 1
+	in <- 2
+	close(in)
+
+	if v, ok := <-out; !ok || v != 2 {
+		t.Errorf("Expected event '%d', got '%d'", 2, v)
+	}
+	if _, ok := <-out; ok {
+		t.Error("Expected output channel to be closed")
+	}
+}
+
+func TestCoalesceChannel(t *testing.T) {
+	in := make(chan int)
+	out := Coalesce(context.Background(), in)
+
+	in <- 1
+	for i := 2; i <= 5; i += 1 {
+		in <- i
+	}
+	close(in)
+
+	last := 0
+	for v := range out {
+		if v <= last {
+			t.Errorf("Expected increasing events, got '%d' after '%d'", v, last)
+		}
+		last = v
+	}
+	if last != 5 {
+		t.Errorf("Expected last event '%d', got '%d'", 5, last)
+	}
+}
+
+func TestThrottleChannel_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Throttle(ctx, in, time.Hour)
+
+	// the leading event is emitted, but never received
+	in <- 1
+	in <- 2
+	cancel()
+
+	// the input is drained, so the producer is not blocked
+	select {
+	case in <- 3:
+	case <-time.After(time.Second):
+		t.Fatal("Expected input to be drained")
+	}
+	close(in)
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected output channel to be closed")
+	}
+}
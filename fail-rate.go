@@ -1,6 +1,8 @@
 package limiter
 
 import (
+	"encoding"
+
 	"github.com/momokatte/go-backoff"
 )
 
@@ -8,15 +10,42 @@ import (
 FailRateLimiter combines a FailLimiter and a RateLimiter to act as a single FailLimiter.
 */
 type FailRateLimiter struct {
+	clock       Clock
 	failLimiter FailLimiter
 	rateLimiter RateLimiter
+	panicPolicy PanicPolicy
 }
 
 /*
 NewFailRateLimiter instantiates a new FailRateLimiter with the provided maximum rate and backoff function.
+
+The rate and backoff function are not validated, for compatibility with earlier versions; BuildFailRateLimiter rejects invalid ones. NewFailRateLimiter panics with an *OptionError only if an option is invalid.
+*/
+func NewFailRateLimiter(maxRate Rate, backOff func(uint) uint, opts ...Option) (l *FailRateLimiter) {
+	o := newOptions(opts)
+	must(firstError(o.validateApplicable(), o.validateClock()))
+	return newFailRateLimiter(maxRate, backOff, o)
+}
+
+/*
+BuildFailRateLimiter instantiates a new FailRateLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithRate and WithBackOff are required.
 */
-func NewFailRateLimiter(maxRate Rate, backOff func(uint) uint) (l *FailRateLimiter) {
-	l = &FailRateLimiter{}
+func BuildFailRateLimiter(opts ...Option) (l *FailRateLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBackOff"), o.validateClock(), o.validateRate(), o.validateBackOff(true)); err != nil {
+		return
+	}
+	l = newFailRateLimiter(o.rate, o.backOffFunc, o)
+	return
+}
+
+func newFailRateLimiter(maxRate Rate, backOff func(uint) uint, o options) (l *FailRateLimiter) {
+	l = &FailRateLimiter{
+		clock:       o.clock,
+		panicPolicy: o.panicPolicy,
+	}
 	l.SetBackOffFunc(backOff)
 	l.SetMaxRate(maxRate)
 	return
@@ -25,8 +54,8 @@ func NewFailRateLimiter(maxRate Rate, backOff func(uint) uint) (l *FailRateLimit
 /*
 NewHalfJitterFailRateLimiter instantiates a new FailRateLimiter with the provided maximum rate, and provdes a half-jitter backoff function with the provided maximum delay.
 */
-func NewHalfJitterFailRateLimiter(maxRate Rate, maxBackOff uint) (l *FailRateLimiter) {
-	return NewFailRateLimiter(maxRate, backoff.HalfJitter(1, maxBackOff))
+func NewHalfJitterFailRateLimiter(maxRate Rate, maxBackOff uint, opts ...Option) (l *FailRateLimiter) {
+	return NewFailRateLimiter(maxRate, backoff.HalfJitter(1, maxBackOff), opts...)
 }
 
 /*
@@ -51,17 +80,42 @@ func (l *FailRateLimiter) Report(success bool) {
 }
 
 /*
-SetBackOffFunc sets a new backoff function for this limiter.
+SetBackOffFunc sets a new backoff function for this limiter. The current failure count is preserved.
 */
 func (l *FailRateLimiter) SetBackOffFunc(f func(uint) uint) {
-	l.failLimiter = NewFailBackOffLimiter(f)
+	if fl, ok := l.failLimiter.(*FailBackOffLimiter); ok {
+		fl.SetBackOffFunc(f)
+		return
+	}
+	l.failLimiter = NewFailBackOffLimiter(f, WithClock(l.clock))
 }
 
 /*
-SetRateLimit sets the maximum rate for this limiter.
+SetMaxRate sets the maximum rate for this limiter. The current rate interval and its usage are preserved.
 */
 func (l *FailRateLimiter) SetMaxRate(rate Rate) {
-	l.rateLimiter = NewBurstRateLimiter(rate)
+	if rl, ok := l.rateLimiter.(*BurstRateLimiter); ok {
+		rl.SetMaxRate(rate)
+		return
+	}
+	l.rateLimiter = NewBurstRateLimiter(rate, WithClock(l.clock))
+}
+
+/*
+Reconfigure applies the WithRate and WithBackOff options to this limiter, preserving the current failure count and rate usage. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *FailRateLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBackOff"), o.validateSet()); err != nil {
+		return
+	}
+	if o.backOffSet {
+		l.SetBackOffFunc(o.backOffFunc)
+	}
+	if o.rateSet {
+		l.SetMaxRate(o.rate)
+	}
+	return
 }
 
 /*
@@ -69,7 +123,42 @@ Invoke enforces this limiter's limits before the invocation of the provided func
 */
 func (l *FailRateLimiter) Invoke(f func() error) (err error) {
 	l.CheckWait()
-	err = f()
-	l.Report(err == nil)
+	return l.panicPolicy.invoke(f, l.Report)
+}
+
+/*
+MarshalBinary encodes the state of the limiter's fail and rate limiters, satisfying the encoding.BinaryMarshaler interface.
+*/
+func (l *FailRateLimiter) MarshalBinary() (b []byte, err error) {
+	e := newStateEncoder(failRateState)
+	for _, sub := range []any{l.failLimiter, l.rateLimiter} {
+		var sb []byte
+		if m, ok := sub.(encoding.BinaryMarshaler); ok {
+			if sb, err = m.MarshalBinary(); err != nil {
+				return
+			}
+		}
+		e.bytes(sb)
+	}
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores the state of the limiter's fail and rate limiters encoded by MarshalBinary.
+*/
+func (l *FailRateLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, failRateState)
+	failState, rateState := d.bytes(), d.bytes()
+	if err = d.finish(); err != nil {
+		return
+	}
+	for i, sub := range []any{l.failLimiter, l.rateLimiter} {
+		sb := [][]byte{failState, rateState}[i]
+		if u, ok := sub.(encoding.BinaryUnmarshaler); ok && len(sb) > 0 {
+			if err = u.UnmarshalBinary(sb); err != nil {
+				return
+			}
+		}
+	}
 	return
 }
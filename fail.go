@@ -10,33 +10,60 @@ FailBackOffLimiter delays execution of subsequent invocations when the caller re
 */
 type FailBackOffLimiter struct {
 	mu          sync.Mutex
+	clock       Clock
 	failCount   uint
 	backOffFunc func(uint) uint
+	recoverFunc func()
+	panicPolicy PanicPolicy
 }
 
 /*
 NewFailBackOffLimiter instantiates a new FailBackOffLimiter with the provided backoff function.
 
-This package provides backoff function builders in go-limiter/backoff.
+This package provides backoff function builders in go-limiter/backoff. The backoff function is not validated, for compatibility with earlier versions, so a nil function panics when a failure is first reported; BuildFailBackOffLimiter rejects it. NewFailBackOffLimiter panics with an *OptionError only if an option is invalid.
 */
-func NewFailBackOffLimiter(backOffFunc func(uint) uint) (l *FailBackOffLimiter) {
-	l = &FailBackOffLimiter{
-		backOffFunc: backOffFunc,
+func NewFailBackOffLimiter(backOffFunc func(uint) uint, opts ...Option) (l *FailBackOffLimiter) {
+	o := newOptions(opts)
+	must(firstError(o.validateApplicable(), o.validateClock()))
+	return newFailBackOffLimiter(backOffFunc, o)
+}
+
+/*
+BuildFailBackOffLimiter instantiates a new FailBackOffLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithBackOff is required.
+*/
+func BuildFailBackOffLimiter(opts ...Option) (l *FailBackOffLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithBackOff"), o.validateClock(), o.validateBackOff(true)); err != nil {
+		return
 	}
+	l = newFailBackOffLimiter(o.backOffFunc, o)
 	return
 }
 
+func newFailBackOffLimiter(backOffFunc func(uint) uint, o options) *FailBackOffLimiter {
+	return &FailBackOffLimiter{
+		clock:       o.clock,
+		backOffFunc: backOffFunc,
+		panicPolicy: o.panicPolicy,
+	}
+}
+
 /*
 CheckWait should be called at the beginning of the caller's action.
 
 It blocks if the limiter needs to restrict execution, otherwise it returns immediately. Restriction is typically based on the last received status, but may also be controlled by other factors.
 */
 func (l *FailBackOffLimiter) CheckWait() {
-	if l.failCount == 0 {
+	l.mu.Lock()
+	failCount, backOffFunc := l.failCount, l.backOffFunc
+	l.mu.Unlock()
+	if failCount == 0 {
 		return
 	}
-	if sleep := l.backOffFunc(l.failCount); sleep > 0 {
-		time.Sleep(time.Duration(sleep) * time.Millisecond)
+	if sleep := backOffFunc(failCount); sleep > 0 {
+		l.clock.Sleep(time.Duration(sleep) * time.Millisecond)
 	}
 }
 
@@ -47,12 +74,18 @@ Failure statuses should be expected to incur rate throttling on subsequent calls
 */
 func (l *FailBackOffLimiter) Report(success bool) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	recovered := false
 	if success && l.failCount > 0 {
 		l.failCount -= 1
+		recovered = l.failCount == 0
 	} else if !success {
 		l.failCount += 1
 	}
+	recoverFunc := l.recoverFunc
+	l.mu.Unlock()
+	if recovered && recoverFunc != nil {
+		recoverFunc()
+	}
 }
 
 /*
@@ -62,7 +95,63 @@ The error returned by the function invocation is returned to the caller without
 */
 func (l *FailBackOffLimiter) Invoke(f func() error) (err error) {
 	l.CheckWait()
-	err = f()
-	l.Report(err == nil)
+	return l.panicPolicy.invoke(f, l.Report)
+}
+
+/*
+SetBackOffFunc sets a new backoff function for this limiter. The current failure count is preserved.
+*/
+func (l *FailBackOffLimiter) SetBackOffFunc(f func(uint) uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backOffFunc = f
+}
+
+/*
+SetRecoverFunc sets a function which is called when a reported success brings the failure count back to zero, such as the Start method of a WarmUp which ramps traffic back up after a failing dependency recovers.
+*/
+func (l *FailBackOffLimiter) SetRecoverFunc(f func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recoverFunc = f
+}
+
+/*
+Reconfigure applies the WithBackOff option to this limiter, preserving the current failure count. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *FailBackOffLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithBackOff"), o.validateSet()); err != nil {
+		return
+	}
+	if o.backOffSet {
+		l.SetBackOffFunc(o.backOffFunc)
+	}
+	return
+}
+
+/*
+MarshalBinary encodes the limiter's failure count, satisfying the encoding.BinaryMarshaler interface.
+*/
+func (l *FailBackOffLimiter) MarshalBinary() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := newStateEncoder(failBackOffState)
+	e.uint(uint64(l.failCount))
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores a failure count encoded by MarshalBinary, so a restarted process continues backing off from a failing dependency.
+*/
+func (l *FailBackOffLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, failBackOffState)
+	failCount := d.uint()
+	if err = d.finish(); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failCount = uint(failCount)
 	return
 }
@@ -62,6 +62,25 @@ func TestFailBackOffLimiter_Invoke(t *testing.T) {
 	}
 }
 
+func TestFailBackOffLimiter_Reconfigure(t *testing.T) {
+	l := NewFailBackOffLimiter(backoff.None)
+	l.Report(false)
+	l.Report(false)
+
+	var failCount uint
+	if err := l.Reconfigure(WithBackOff(func(n uint) uint { failCount = n; return 0 })); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	l.CheckWait()
+	if failCount != 2 {
+		t.Errorf("Expected 2, got %d", failCount)
+	}
+
+	if err := l.Reconfigure(WithBackOff(nil)); !errors.Is(err, ErrNilBackOffFunc) {
+		t.Errorf("Expected '%v', got '%v'", ErrNilBackOffFunc, err)
+	}
+}
+
 func BenchmarkBackOffLimiterSuccess(b *testing.B) {
 	l := NewFailBackOffLimiter(backoff.None)
 	for i := 0; i < b.N; i++ {
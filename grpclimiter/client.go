@@ -0,0 +1,146 @@
+package grpclimiter
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/momokatte/go-limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+UnaryClientInterceptor returns a client interceptor which waits on the applicable Limits before each unary call and reports the outcome of the call to their fail limiters.
+
+Calls which fail with codes.Unavailable or codes.ResourceExhausted are reported as failures; all other outcomes are reported as successes, since they do not indicate that the server is limiting requests. If the call's context is done while waiting on the Limits, the call is not made, and its status is returned as by status.FromContextError.
+*/
+func (i *Interceptors) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		ls := i.limitsFor(ctx, method)
+		held, err := acquire(ctx, ls)
+		if err != nil {
+			return
+		}
+		defer held.release()
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		report(ls, err)
+		return
+	}
+}
+
+/*
+StreamClientInterceptor returns a client interceptor which waits on the applicable Limits before opening a stream, holds their tokens for the lifetime of the stream, and enforces their message rates on every message sent or received.
+
+The stream's outcome is reported to the fail limiters when RecvMsg returns an error (including io.EOF on success), when a non-server-streaming call receives its response, or when the stream's context is done. If the context is done while waiting on the Limits, the stream is not opened, and its status is returned as by status.FromContextError.
+*/
+func (i *Interceptors) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (cs grpc.ClientStream, err error) {
+		ls := i.limitsFor(ctx, method)
+		held, err := acquire(ctx, ls)
+		if err != nil {
+			return
+		}
+		opened := false
+		defer func() {
+			if !opened {
+				held.release()
+			}
+		}()
+		if cs, err = streamer(ctx, desc, cc, method, opts...); err != nil {
+			report(ls, err)
+			return
+		}
+		opened = true
+		s := &clientStream{
+			ClientStream:  cs,
+			limits:        ls,
+			held:          held,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.finish(ctx.Err())
+			case <-s.done:
+			}
+		}()
+		cs = s
+		return
+	}
+}
+
+// acquire waits on rate limits, tokens and fail limiters, in that order, until the context is done. If it is done first, any tokens acquired are released and its status error is returned.
+func acquire(ctx context.Context, ls []*Limits) (held *heldTokens, err error) {
+	held = &heldTokens{}
+	for _, l := range ls {
+		if l.Rate != nil {
+			err = limiter.CheckWaitContext(ctx, l.Rate)
+		}
+		if l.Tokens != nil && err == nil {
+			var token *[16]byte
+			if token, err = limiter.AcquireTokenContext(ctx, l.Tokens); err == nil {
+				held.add(l, token)
+			}
+		}
+		if l.Fail != nil && err == nil {
+			err = limiter.CheckWaitContext(ctx, l.Fail)
+		}
+		if err != nil {
+			held.release()
+			held = nil
+			err = status.FromContextError(err).Err()
+			return
+		}
+	}
+	return
+}
+
+func report(ls []*Limits, err error) {
+	success := true
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		success = false
+	}
+	for _, l := range ls {
+		if l.Fail != nil {
+			l.Fail.Report(success)
+		}
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	limits        []*Limits
+	held          *heldTokens
+	serverStreams bool
+	once          sync.Once
+	done          chan struct{}
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	checkMessage(s.limits)
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *clientStream) RecvMsg(m interface{}) (err error) {
+	checkMessage(s.limits)
+	err = s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.finish(err)
+	}
+	return
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		report(s.limits, err)
+		s.held.release()
+		close(s.done)
+	})
+}
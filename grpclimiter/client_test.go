@@ -0,0 +1,172 @@
+package grpclimiter
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type countingFailLimiter struct {
+	waits, successes, failures int
+}
+
+func (l *countingFailLimiter) CheckWait() {
+	l.waits += 1
+}
+
+func (l *countingFailLimiter) Report(success bool) {
+	if success {
+		l.successes += 1
+	} else {
+		l.failures += 1
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	ctx  context.Context
+	recv []error
+}
+
+func (s *fakeClientStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error {
+	return nil
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) (err error) {
+	err, s.recv = s.recv[0], s.recv[1:]
+	return
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	fl := &countingFailLimiter{}
+	i := NewInterceptors(&Limits{Fail: fl})
+	interceptor := i.UnaryClientInterceptor()
+
+	for _, err := range []error{
+		nil,
+		status.Error(codes.NotFound, "not found"),
+		status.Error(codes.Unavailable, "unavailable"),
+		status.Error(codes.ResourceExhausted, "exhausted"),
+	} {
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			return err
+		}
+		if actual := interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, invoker); actual != err {
+			t.Errorf("Expected '%v', got '%v'", err, actual)
+		}
+	}
+
+	if fl.waits != 4 {
+		t.Errorf("Expected 4, got %d", fl.waits)
+	}
+	if fl.successes != 2 {
+		t.Errorf("Expected 2, got %d", fl.successes)
+	}
+	if fl.failures != 2 {
+		t.Errorf("Expected 2, got %d", fl.failures)
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	fl := &countingFailLimiter{}
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: tl, Fail: fl})
+	interceptor := i.StreamClientInterceptor()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+
+	fcs := &fakeClientStream{ctx: context.Background(), recv: []error{nil, nil, status.Error(codes.Unavailable, "unavailable")}}
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return fcs, nil
+	}
+	cs, err := interceptor(context.Background(), desc, nil, "/test.Service/Stream", streamer)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if _, ok := tl.TryAcquireToken(); ok {
+		t.Fatal("Expected token to be held for the stream lifetime")
+	}
+	for cs.RecvMsg(nil) == nil {
+	}
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released")
+	}
+	if fl.failures != 1 {
+		t.Errorf("Expected 1, got %d", fl.failures)
+	}
+}
+
+func TestStreamClientInterceptor_Canceled(t *testing.T) {
+	fl := &countingFailLimiter{}
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: tl, Fail: fl})
+	interceptor := i.StreamClientInterceptor()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{ctx: ctx, recv: []error{io.EOF}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := interceptor(ctx, desc, nil, "/test.Service/Stream", streamer); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	cancel()
+
+	// the canceled stream releases its token
+	token := tl.AcquireToken()
+	tl.ReleaseToken(token)
+	if fl.successes != 1 {
+		t.Errorf("Expected 1, got %d", fl.successes)
+	}
+}
+
+func TestUnaryClientInterceptor_Canceled(t *testing.T) {
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: tl})
+	interceptor := i.UnaryClientInterceptor()
+	invoked := false
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		invoked = true
+		return nil
+	}
+
+	// the only token is held, so the call waits until its deadline
+	token := tl.AcquireToken()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := interceptor(ctx, "/test.Service/Method", nil, nil, nil, invoker); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected %d, got '%v'", codes.DeadlineExceeded, err)
+	}
+	if invoked {
+		t.Error("Expected call not to be made")
+	}
+	tl.ReleaseToken(token)
+}
+
+func TestUnaryClientInterceptor_Panic(t *testing.T) {
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: tl})
+	interceptor := i.UnaryClientInterceptor()
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		panic("boom")
+	}
+
+	func() {
+		defer func() {
+			recover()
+		}()
+		interceptor(context.Background(), "/test.Service/Method", nil, nil, nil, invoker)
+	}()
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released after a panic")
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Package grpclimiter provides gRPC server and client interceptors which enforce go-limiter limits on unary calls and streams.
+*/
+package grpclimiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/momokatte/go-limiter"
+	"google.golang.org/grpc/metadata"
+)
+
+/*
+Limits is a set of limiters applied to gRPC calls. Any of the limiters may be nil.
+
+Tokens is held for the duration of a unary call or for the lifetime of a stream. Rate is checked once per unary call or stream. MessageRate is checked for every message sent or received on a stream. Fail is consulted and receives outcome reports on the client side only.
+
+On the server side, limiters which provide non-blocking TryAcquireToken or Allow methods (like TokenChanLimiter and BurstRateLimiter) cause calls to be rejected when limits are hit; other limiters block the call until it may proceed.
+*/
+type Limits struct {
+	Tokens      limiter.TokenLimiter
+	Rate        limiter.RateLimiter
+	MessageRate limiter.RateLimiter
+	Fail        limiter.FailLimiter
+}
+
+/*
+KeyFunc derives a limiting key from a call's context and full method name. An empty key means the call is not subject to keyed limits.
+*/
+type KeyFunc func(ctx context.Context, fullMethod string) string
+
+/*
+IncomingMetadataKey returns a KeyFunc which uses the first value of the named header in the incoming metadata, for use with server interceptors.
+*/
+func IncomingMetadataKey(name string) KeyFunc {
+	return func(ctx context.Context, _ string) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return firstValue(md, name)
+	}
+}
+
+/*
+OutgoingMetadataKey returns a KeyFunc which uses the first value of the named header in the outgoing metadata, for use with client interceptors.
+*/
+func OutgoingMetadataKey(name string) KeyFunc {
+	return func(ctx context.Context, _ string) string {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return firstValue(md, name)
+	}
+}
+
+func firstValue(md metadata.MD, name string) string {
+	if vals := md.Get(name); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+/*
+DefaultMaxKeys is the number of keys for which an Interceptors retains Limits unless SetMaxKeys is called.
+*/
+const DefaultMaxKeys = 10000
+
+/*
+Interceptors holds default, per-method and per-key Limits and produces gRPC interceptors which enforce them.
+
+A call is subject to the Limits registered for its full method name (or the default Limits if there are none), and additionally to the Limits for its key when keyed limiting is configured.
+*/
+type Interceptors struct {
+	mu        sync.Mutex
+	defaults  *Limits
+	methods   map[string]*Limits
+	keyFunc   KeyFunc
+	newLimits func(key string) *Limits
+	maxKeys   int
+	keys      map[string]*list.Element
+	recent    *list.List
+}
+
+// keyedLimits is an element of the recently used keys list.
+type keyedLimits struct {
+	key    string
+	limits *Limits
+}
+
+/*
+NewInterceptors instantiates a new Interceptors with the provided default Limits, which may be nil.
+*/
+func NewInterceptors(defaults *Limits) (i *Interceptors) {
+	i = &Interceptors{
+		defaults: defaults,
+		methods:  make(map[string]*Limits),
+		maxKeys:  DefaultMaxKeys,
+		keys:     make(map[string]*list.Element),
+		recent:   list.New(),
+	}
+	return
+}
+
+/*
+SetMethodLimits sets the Limits for the provided full method name (like "/package.Service/Method"), replacing the default Limits for that method.
+*/
+func (i *Interceptors) SetMethodLimits(fullMethod string, l *Limits) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.methods[fullMethod] = l
+}
+
+/*
+SetKeyedLimits enables keyed limiting. The KeyFunc derives a key for each call, and the newLimits function is called to create the Limits shared by all calls with that key when the key is first seen.
+
+Keys may be controlled by clients, so the Limits of at most the number of keys set by SetMaxKeys are retained; when a new key would exceed it, the least recently used key's Limits are discarded, and are created anew if that key is seen again.
+*/
+func (i *Interceptors) SetKeyedLimits(keyFunc KeyFunc, newLimits func(key string) *Limits) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keyFunc = keyFunc
+	i.newLimits = newLimits
+	i.keys = make(map[string]*list.Element)
+	i.recent.Init()
+}
+
+/*
+SetMaxKeys sets the number of keys for which Limits are retained, discarding the least recently used keys' Limits if there are more. A value less than 1 restores DefaultMaxKeys.
+
+Calls in progress continue to use the Limits they started with, so a key which is discarded and seen again while its earlier calls are in progress is briefly subject to two sets of Limits. The maximum should be well above the number of keys expected to be active at once.
+*/
+func (i *Interceptors) SetMaxKeys(n int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if n < 1 {
+		n = DefaultMaxKeys
+	}
+	i.maxKeys = n
+	i.evictKeys()
+}
+
+func (i *Interceptors) limitsFor(ctx context.Context, fullMethod string) (ls []*Limits) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if l, ok := i.methods[fullMethod]; ok {
+		ls = appendLimits(ls, l)
+	} else {
+		ls = appendLimits(ls, i.defaults)
+	}
+	if i.keyFunc == nil {
+		return
+	}
+	key := i.keyFunc(ctx, fullMethod)
+	if key == "" {
+		return
+	}
+	var l *Limits
+	if e, ok := i.keys[key]; ok {
+		i.recent.MoveToFront(e)
+		l = e.Value.(*keyedLimits).limits
+	} else {
+		l = i.newLimits(key)
+		i.keys[key] = i.recent.PushFront(&keyedLimits{key, l})
+		i.evictKeys()
+	}
+	ls = appendLimits(ls, l)
+	return
+}
+
+// evictKeys discards the least recently used keys' Limits until no more than maxKeys remain. The caller must hold mu.
+func (i *Interceptors) evictKeys() {
+	for len(i.keys) > i.maxKeys {
+		e := i.recent.Back()
+		i.recent.Remove(e)
+		delete(i.keys, e.Value.(*keyedLimits).key)
+	}
+}
+
+func appendLimits(ls []*Limits, l *Limits) []*Limits {
+	if l == nil {
+		return ls
+	}
+	return append(ls, l)
+}
+
+// heldTokens records tokens acquired from a set of Limits so they can be released together.
+type heldTokens struct {
+	once   sync.Once
+	limits []*Limits
+	tokens []*[16]byte
+}
+
+func (h *heldTokens) add(l *Limits, token *[16]byte) {
+	h.limits = append(h.limits, l)
+	h.tokens = append(h.tokens, token)
+}
+
+func (h *heldTokens) release() {
+	h.once.Do(func() {
+		for j := len(h.tokens) - 1; j >= 0; j -= 1 {
+			h.limits[j].Tokens.ReleaseToken(h.tokens[j])
+		}
+	})
+}
+
+// checkMessage enforces the message rate of every Limits in the set.
+func checkMessage(ls []*Limits) {
+	for _, l := range ls {
+		if l.MessageRate != nil {
+			l.MessageRate.CheckWait()
+		}
+	}
+}
+
+func hasMessageRate(ls []*Limits) bool {
+	for _, l := range ls {
+		if l.MessageRate != nil {
+			return true
+		}
+	}
+	return false
+}
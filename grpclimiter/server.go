@@ -0,0 +1,136 @@
+package grpclimiter
+
+import (
+	"context"
+
+	"github.com/momokatte/go-limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type tokenTrier interface {
+	TryAcquireToken() (token *[16]byte, ok bool)
+}
+
+type allower interface {
+	Allow() bool
+}
+
+type refunder interface {
+	Refund()
+}
+
+/*
+UnaryServerInterceptor returns a server interceptor which enforces the applicable Limits around each unary call.
+
+Calls which exceed a rate limit are rejected with codes.ResourceExhausted, and calls which cannot acquire a token are rejected with codes.Unavailable. Limits are checked without blocking before any blocking limiter is waited on; when a call is rejected, its tokens are released and the rate budget it consumed is returned to limiters which provide a Refund method (like BurstRateLimiter and QuotaLimiter). If the call's context is done while waiting on a blocking limiter, the call is rejected in the same way, with its status as by status.FromContextError.
+*/
+func (i *Interceptors) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		held, err := admit(ctx, i.limitsFor(ctx, info.FullMethod))
+		if err != nil {
+			return
+		}
+		defer held.release()
+		resp, err = handler(ctx, req)
+		return
+	}
+}
+
+/*
+StreamServerInterceptor returns a server interceptor which enforces the applicable Limits when a stream is opened, holds their tokens for the lifetime of the stream, and enforces their message rates on every message sent or received.
+
+Streams which exceed a rate limit are rejected with codes.ResourceExhausted, and streams which cannot acquire a token are rejected with codes.Unavailable. Streams whose context is done while waiting on a blocking limiter are rejected with its status, as by status.FromContextError.
+*/
+func (i *Interceptors) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ls := i.limitsFor(ss.Context(), info.FullMethod)
+		held, err := admit(ss.Context(), ls)
+		if err != nil {
+			return
+		}
+		defer held.release()
+		if hasMessageRate(ls) {
+			ss = &serverStream{ServerStream: ss, limits: ls}
+		}
+		err = handler(srv, ss)
+		return
+	}
+}
+
+// admit checks rate limits and acquires tokens without blocking where the limiters allow it. Non-blocking checks are made first, so that a rejected call has not waited on any limiter and its consumed rate budget can be refunded; blocking limiters reject a call only when its context is done, so they are waited on last.
+func admit(ctx context.Context, ls []*Limits) (held *heldTokens, err error) {
+	var allowed []allower
+	refund := func() {
+		for _, a := range allowed {
+			if r, ok := a.(refunder); ok {
+				r.Refund()
+			}
+		}
+	}
+	for _, l := range ls {
+		if a, ok := l.Rate.(allower); ok {
+			if !a.Allow() {
+				refund()
+				err = status.Error(codes.ResourceExhausted, "Rate limit has been exceeded.")
+				return
+			}
+			allowed = append(allowed, a)
+		}
+	}
+	held = &heldTokens{}
+	for _, l := range ls {
+		if t, ok := l.Tokens.(tokenTrier); ok {
+			token, ok := t.TryAcquireToken()
+			if !ok {
+				held.release()
+				held = nil
+				refund()
+				err = status.Error(codes.Unavailable, "Concurrency limit has been reached.")
+				return
+			}
+			held.add(l, token)
+		}
+	}
+	canceled := func(ctxErr error) {
+		held.release()
+		held = nil
+		refund()
+		err = status.FromContextError(ctxErr).Err()
+	}
+	for _, l := range ls {
+		if _, ok := l.Rate.(allower); !ok && l.Rate != nil {
+			if ctxErr := limiter.CheckWaitContext(ctx, l.Rate); ctxErr != nil {
+				canceled(ctxErr)
+				return
+			}
+		}
+	}
+	for _, l := range ls {
+		if _, ok := l.Tokens.(tokenTrier); !ok && l.Tokens != nil {
+			token, ctxErr := limiter.AcquireTokenContext(ctx, l.Tokens)
+			if ctxErr != nil {
+				canceled(ctxErr)
+				return
+			}
+			held.add(l, token)
+		}
+	}
+	return
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	limits []*Limits
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	checkMessage(s.limits)
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	checkMessage(s.limits)
+	return s.ServerStream.RecvMsg(m)
+}
@@ -0,0 +1,227 @@
+package grpclimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	return nil
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	return nil
+}
+
+func TestUnaryServerInterceptor_Tokens(t *testing.T) {
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: tl})
+	interceptor := i.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	var innerErr error
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		// the only token is held, so a nested call must be rejected
+		_, innerErr = interceptor(ctx, req, info, func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+		return "ok", nil
+	}
+	resp, err := interceptor(context.Background(), nil, info, handler)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if resp != "ok" {
+		t.Errorf("Expected 'ok', got '%v'", resp)
+	}
+	if code := status.Code(innerErr); code != codes.Unavailable {
+		t.Errorf("Expected %d, got %d", codes.Unavailable, code)
+	}
+
+	// the token must have been released
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestUnaryServerInterceptor_MethodRate(t *testing.T) {
+	i := NewInterceptors(nil)
+	i.SetMethodLimits("/test.Service/Limited", &Limits{Rate: limiter.NewBurstRateLimiter(limiter.NewRate(1, time.Hour))})
+	interceptor := i.UnaryServerInterceptor()
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	limited := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Limited"}
+	if _, err := interceptor(context.Background(), nil, limited, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	_, err := interceptor(context.Background(), nil, limited, handler)
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Errorf("Expected %d, got %d", codes.ResourceExhausted, code)
+	}
+
+	unlimited := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Unlimited"}
+	for j := 0; j < 3; j += 1 {
+		if _, err := interceptor(context.Background(), nil, unlimited, handler); err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_Keyed(t *testing.T) {
+	i := NewInterceptors(nil)
+	created := 0
+	i.SetKeyedLimits(IncomingMetadataKey("x-tenant"), func(key string) *Limits {
+		created += 1
+		return &Limits{Rate: limiter.NewBurstRateLimiter(limiter.NewRate(1, time.Hour))}
+	})
+	interceptor := i.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	ctxA := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "a"))
+	ctxB := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "b"))
+
+	if _, err := interceptor(ctxA, nil, info, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := interceptor(ctxB, nil, info, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := interceptor(ctxA, nil, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected %d, got %d", codes.ResourceExhausted, status.Code(err))
+	}
+	// calls without a key are not subject to keyed limits
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if created != 2 {
+		t.Errorf("Expected 2, got %d", created)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	tl := limiter.NewTokenChanLimiter(1)
+	messages := 0
+	i := NewInterceptors(&Limits{Tokens: tl, MessageRate: rateFunc(func() { messages += 1 })})
+	interceptor := i.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+	ss := &fakeServerStream{ctx: context.Background()}
+
+	err := interceptor(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		if _, ok := tl.TryAcquireToken(); ok {
+			t.Error("Expected token to be held for the stream lifetime")
+		}
+		stream.RecvMsg(nil)
+		stream.SendMsg(nil)
+		stream.SendMsg(nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if messages != 3 {
+		t.Errorf("Expected 3, got %d", messages)
+	}
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released")
+	}
+}
+
+type rateFunc func()
+
+func (f rateFunc) CheckWait() {
+	f()
+}
+
+func TestUnaryServerInterceptor_RefundsRejected(t *testing.T) {
+	rl := limiter.NewBurstRateLimiter(limiter.NewRate(1, time.Hour))
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Rate: rl, Tokens: tl})
+	interceptor := i.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	// the call is rejected for want of a token, so its rate budget must be refunded
+	token := tl.AcquireToken()
+	if _, err := interceptor(context.Background(), nil, info, handler); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected %d, got %d", codes.Unavailable, status.Code(err))
+	}
+	tl.ReleaseToken(token)
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestInterceptors_MaxKeys(t *testing.T) {
+	i := NewInterceptors(nil)
+	created := map[string]int{}
+	i.SetKeyedLimits(IncomingMetadataKey("x-tenant"), func(key string) *Limits {
+		created[key] += 1
+		return &Limits{}
+	})
+	i.SetMaxKeys(2)
+	interceptor := i.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+	call := func(key string) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", key))
+		if _, err := interceptor(ctx, nil, info, handler); err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+	}
+
+	call("a")
+	call("b")
+	call("a")
+	// "b" is the least recently used key, so it is discarded
+	call("c")
+	if len(i.keys) != 2 {
+		t.Errorf("Expected 2, got %d", len(i.keys))
+	}
+	call("a")
+	call("b")
+	if created["a"] != 1 || created["b"] != 2 {
+		t.Errorf("Expected a created once and b twice, got %v", created)
+	}
+}
+
+// blockingTokens hides TryAcquireToken, so the server interceptor must wait for a token.
+type blockingTokens struct {
+	limiter.TokenLimiter
+}
+
+func TestUnaryServerInterceptor_Canceled(t *testing.T) {
+	tl := limiter.NewTokenChanLimiter(1)
+	i := NewInterceptors(&Limits{Tokens: blockingTokens{tl}})
+	interceptor := i.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		t.Error("Expected handler not to be called")
+		return nil, nil
+	}
+
+	token := tl.AcquireToken()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.Canceled {
+		t.Errorf("Expected %d, got '%v'", codes.Canceled, err)
+	}
+	tl.ReleaseToken(token)
+}
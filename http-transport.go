@@ -0,0 +1,213 @@
+package limiter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errFailStatus is returned from the limited invocation so the limiter records a failure, and is never returned to the caller.
+var errFailStatus = errors.New("Response status indicates server is limiting requests.")
+
+/*
+LimitedTransport enforces a limiter's limits around outbound HTTP requests and satisfies the http.RoundTripper interface.
+
+Transport errors and responses with status 429 (Too Many Requests) or 503 (Service Unavailable) are reported to the limiter as failures. When a response carries a Retry-After header, or RateLimit-Remaining of 0 with a RateLimit-Reset header, all requests through the transport are paused for the period specified by the server.
+*/
+type LimitedTransport struct {
+	mu          sync.Mutex
+	clock       Clock
+	next        http.RoundTripper
+	invoke      func(f func() error) error
+	rateSetter  RateSetter
+	serverRate  Rate
+	pausedUntil time.Time
+}
+
+/*
+NewLimitedTransport instantiates a new LimitedTransport which wraps the provided RoundTripper and invokes it through the provided InvocationLimiter.
+
+If next is nil, http.DefaultTransport is used.
+*/
+func NewLimitedTransport(next http.RoundTripper, l InvocationLimiter, opts ...Option) (t *LimitedTransport) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t = &LimitedTransport{
+		clock:  newOptions(opts).clock,
+		next:   next,
+		invoke: l.Invoke,
+	}
+	return
+}
+
+/*
+NewTokenFailTransport instantiates a new LimitedTransport which wraps the provided RoundTripper, holding a token from the provided TokenAndFailLimiter for the duration of each request and reporting its outcome.
+
+If next is nil, http.DefaultTransport is used.
+*/
+func NewTokenFailTransport(next http.RoundTripper, l TokenAndFailLimiter, opts ...Option) (t *LimitedTransport) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	o := newOptions(opts)
+	t = &LimitedTransport{
+		clock: o.clock,
+		next:  next,
+		invoke: func(f func() error) (err error) {
+			token := l.AcquireToken()
+			return o.panicPolicy.invoke(f, func(success bool) {
+				l.ReleaseTokenAndReport(token, success)
+			})
+		},
+	}
+	return
+}
+
+/*
+SetRateSetter sets a RateSetter, such as a BurstRateLimiter, whose maximum rate will be adapted to the limit advertised by the server in RateLimit-Limit and RateLimit-Policy response headers.
+
+The rate is only set when the server advertises both a limit and a window (the "w" parameter), and only when the advertised rate changes.
+*/
+func (t *LimitedTransport) SetRateSetter(s RateSetter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rateSetter = s
+	t.serverRate = Rate{}
+}
+
+/*
+PausedUntil returns the time until which requests are paused at the server's request. The zero time is returned if the server has never requested a pause.
+*/
+func (t *LimitedTransport) PausedUntil() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pausedUntil
+}
+
+/*
+RoundTrip waits out any pause requested by the server and then executes the request through the limiter.
+
+If the request's context is canceled while paused, the context error is returned and the request is not sent. Limited responses (429 and 503) are returned to the caller without an error, so the caller can inspect them.
+*/
+func (t *LimitedTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if err = t.waitPause(req); err != nil {
+		return
+	}
+	err = t.invoke(func() (rtErr error) {
+		if resp, rtErr = t.next.RoundTrip(req); rtErr != nil {
+			return
+		}
+		t.observe(resp)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			rtErr = errFailStatus
+		}
+		return
+	})
+	if err == errFailStatus {
+		err = nil
+	}
+	return
+}
+
+func (t *LimitedTransport) waitPause(req *http.Request) error {
+	ctx := req.Context()
+	for {
+		d := t.PausedUntil().Sub(t.clock.Now())
+		if d <= 0 {
+			return nil
+		}
+		timer := t.clock.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+func (t *LimitedTransport) observe(resp *http.Response) {
+	now := t.clock.Now()
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		t.pauseUntil(now.Add(d))
+	}
+	if remaining, ok := parseRateLimitCount(resp.Header.Get("RateLimit-Remaining")); ok && remaining == 0 {
+		if reset, ok := parseRateLimitCount(resp.Header.Get("RateLimit-Reset")); ok {
+			t.pauseUntil(now.Add(time.Duration(reset) * time.Second))
+		}
+	}
+	t.adaptRate(resp.Header)
+}
+
+func (t *LimitedTransport) pauseUntil(until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+func (t *LimitedTransport) adaptRate(h http.Header) {
+	limit, ok := parseRateLimitCount(h.Get("RateLimit-Limit"))
+	if !ok || limit <= 0 {
+		return
+	}
+	window, ok := parseRateLimitWindow(h.Get("RateLimit-Policy"))
+	if !ok {
+		window, ok = parseRateLimitWindow(h.Get("RateLimit-Limit"))
+	}
+	if !ok {
+		return
+	}
+	rate := NewRate(limit, time.Duration(window)*time.Second)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rateSetter == nil || rate == t.serverRate {
+		return
+	}
+	t.serverRate = rate
+	t.rateSetter.SetMaxRate(rate)
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (d time.Duration, ok bool) {
+	if v = strings.TrimSpace(v); v == "" {
+		return
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now), true
+	}
+	return
+}
+
+// parseRateLimitCount parses the leading integer of a RateLimit-* header value, ignoring any policy parameters which follow it.
+func parseRateLimitCount(v string) (n int, ok bool) {
+	if i := strings.IndexAny(v, ",;"); i >= 0 {
+		v = v[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	return n, err == nil && n >= 0
+}
+
+// parseRateLimitWindow parses the "w" parameter of a RateLimit-Policy header value such as "100;w=60".
+func parseRateLimitWindow(v string) (secs int, ok bool) {
+	for _, param := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' }) {
+		param = strings.TrimSpace(param)
+		if !strings.HasPrefix(param, "w=") {
+			continue
+		}
+		secs, err := strconv.Atoi(param[2:])
+		return secs, err == nil && secs > 0
+	}
+	return
+}
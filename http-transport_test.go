@@ -0,0 +1,175 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+type recordingRateSetter struct {
+	rates []Rate
+}
+
+func (s *recordingRateSetter) SetMaxRate(rate Rate) {
+	s.rates = append(s.rates, rate)
+}
+
+func TestLimitedTransport(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	fl := NewFailBackOffLimiter(backoff.None)
+	client := &http.Client{Transport: NewLimitedTransport(nil, fl)}
+
+	for _, status = range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected %d, got %d", status, resp.StatusCode)
+		}
+	}
+	if fl.failCount != 2 {
+		t.Errorf("Expected 2, got %d", fl.failCount)
+	}
+
+	status = http.StatusOK
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	resp.Body.Close()
+	if fl.failCount != 1 {
+		t.Errorf("Expected 1, got %d", fl.failCount)
+	}
+}
+
+func TestLimitedTransport_TransportError(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	rt := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("error")
+	})
+	tl := NewTokenFailLimiter(NewTokenChanLimiter(1), fl)
+	client := &http.Client{Transport: NewTokenFailTransport(rt, tl)}
+
+	if _, err := client.Get("http://example.invalid/"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if fl.failCount != 1 {
+		t.Errorf("Expected 1, got %d", fl.failCount)
+	}
+}
+
+func TestLimitedTransport_RetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	lt := NewLimitedTransport(nil, NewTokenChanLimiter(1), WithClock(c))
+	client := &http.Client{Transport: lt}
+
+	start := c.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	resp.Body.Close()
+
+	if paused := lt.PausedUntil().Sub(start); paused != 30*time.Second {
+		t.Fatalf("Expected pause of 30s, got %s", paused)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		c.BlockUntil(1)
+		cancel()
+	}()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Errorf("Unexpected error, got: %s", err.Error())
+		} else {
+			resp.Body.Close()
+		}
+		close(done)
+	}()
+	c.BlockUntil(1)
+	c.Advance(30 * time.Second)
+	<-done
+}
+
+func TestLimitedTransport_RateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Limit", "100")
+		w.Header().Set("RateLimit-Policy", "100;w=60")
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", "5")
+	}))
+	defer srv.Close()
+
+	rs := &recordingRateSetter{}
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	lt := NewLimitedTransport(nil, NewTokenChanLimiter(1), WithClock(c))
+	lt.SetRateSetter(rs)
+	client := &http.Client{Transport: lt}
+
+	start := c.Now()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	resp.Body.Close()
+
+	if paused := lt.PausedUntil().Sub(start); paused != 5*time.Second {
+		t.Errorf("Expected pause of 5s, got %s", paused)
+	}
+	if len(rs.rates) != 1 {
+		t.Fatalf("Expected 1 rate, got %d", len(rs.rates))
+	}
+	if expected := NewRate(100, time.Minute); rs.rates[0] != expected {
+		t.Errorf("Expected %v, got %v", expected, rs.rates[0])
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		"Tue, 01 Jan 2019 00:00:10 GMT": 10 * time.Second,
+	}
+	for v, expected := range tests {
+		if d, ok := parseRetryAfter(v, now); !ok || d != expected {
+			t.Errorf("%q: expected %s, got %s", v, expected, d)
+		}
+	}
+	for _, v := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(v, now); ok {
+			t.Errorf("%q: expected failure", v)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -3,6 +3,10 @@ Package limiter provides interfaces for various execution-limiting needs, along
 */
 package limiter
 
+import (
+	"encoding"
+)
+
 /*
 TokenLimiter is the interface that wraps the AcquireToken and ReleaseToken methods, representing the use of a token mechanism to enforce concurrency limits.
 
@@ -17,6 +21,8 @@ The token type is a pointer to a 16-byte array (128 bits) to give limiter implem
 - Hexadecimal string UUIDs can be converted to/from []byte using the "encoding/hex" package.
 
 - Numeric values can be converted to/from []byte using the "encoding/binary" package.
+
+The limiters in this package fill the first 8 bytes of each token with a big-endian sequential ID. Callers who want structured metadata should use Acquire, which wraps the raw token in a Token handle.
 */
 type TokenLimiter interface {
 	AcquireToken() (token *[16]byte)
@@ -63,7 +69,54 @@ type TokenAndFailLimiter interface {
 InvocationLimiter is the interface that wraps the Invoke method.
 
 Invoke enforces the limiter's limits around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification, and its existence may be used by the limiter to delay the current return or subsequent invocations.
+
+If the function panics, the limiter's implementations release any held token and report a failure before continuing to panic, or before returning a *PanicError under the ConvertPanic policy.
 */
 type InvocationLimiter interface {
 	Invoke(f func() error) error
 }
+
+/*
+RateSetter is the interface that wraps the SetMaxRate method.
+
+SetMaxRate sets a new rate threshold for the limiter, typically in response to feedback from the resource being limited.
+*/
+type RateSetter interface {
+	SetMaxRate(rate Rate)
+}
+
+/*
+AdjustableRateLimiter is the interface that groups the CheckWait and SetMaxRate methods of rate limiters whose threshold can be changed while in use, like BurstRateLimiter and BucketRateLimiter.
+*/
+type AdjustableRateLimiter interface {
+	RateLimiter
+	RateSetter
+}
+
+/*
+WeightedRateLimiter is the interface that wraps the CheckWaitN method, representing the use of a delay mechanism to enforce a rate limit on quantities rather than actions.
+
+CheckWaitN should be called with the quantity (such as a number of bytes) consumed by the caller's action. It blocks if the limiter needs to restrict execution, otherwise it returns immediately.
+*/
+type WeightedRateLimiter interface {
+	CheckWaitN(n int)
+}
+
+/*
+Reconfigurable is the interface that wraps the Reconfigure method.
+
+Reconfigure applies the provided options to a live limiter, preserving its current usage and failure state. All options are validated before any are applied, and an *OptionError is returned for the first invalid option. An option which does not apply to the limiter is rejected with an *OptionError wrapping ErrInapplicableOption.
+*/
+type Reconfigurable interface {
+	Reconfigure(opts ...Option) error
+}
+
+/*
+StatefulLimiter is the interface that groups the MarshalBinary and UnmarshalBinary methods of limiters whose usage or failure state can be persisted across process restarts.
+
+MarshalBinary encodes the limiter's current state, but not its configuration. UnmarshalBinary replaces the limiter's state with a previously encoded state, returning ErrInvalidState if it was encoded by a different type of limiter. Times are restored as absolute times, so the state reflects the time which has passed since it was encoded.
+*/
+type StatefulLimiter interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
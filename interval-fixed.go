@@ -6,26 +6,108 @@ import (
 )
 
 type FixedIntervalLimiter struct {
-	mu       sync.Mutex
-	interval time.Duration
-	last     time.Time
+	mu          sync.Mutex
+	clock       Clock
+	stateMu     sync.Mutex
+	last        time.Time
+	configMu    sync.Mutex
+	interval    time.Duration
+	panicPolicy PanicPolicy
 }
 
-func NewFixedIntervalLimiter(interval time.Duration) *FixedIntervalLimiter {
+func NewFixedIntervalLimiter(interval time.Duration, opts ...Option) *FixedIntervalLimiter {
+	// the interval is not validated, for compatibility with earlier versions
+	o := newOptions(opts)
+	must(firstError(o.validateApplicable(), o.validateClock()))
+	return newFixedIntervalLimiter(interval, o)
+}
+
+func BuildFixedIntervalLimiter(opts ...Option) (l *FixedIntervalLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithInterval"), o.validateClock(), o.validateInterval()); err != nil {
+		return
+	}
+	l = newFixedIntervalLimiter(o.interval, o)
+	return
+}
+
+func newFixedIntervalLimiter(interval time.Duration, o options) *FixedIntervalLimiter {
 	return &FixedIntervalLimiter{
-		interval: interval,
+		clock:       o.clock,
+		interval:    interval,
+		panicPolicy: o.panicPolicy,
+	}
+}
+
+func (l *FixedIntervalLimiter) SetInterval(d time.Duration) {
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	l.interval = d
+}
+
+/*
+Reconfigure applies the WithInterval option to this limiter, preserving the time of the last permitted action. Callers already waiting are not affected. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *FixedIntervalLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithInterval"), o.validateSet()); err != nil {
+		return
 	}
+	if o.intervalSet {
+		l.SetInterval(o.interval)
+	}
+	return
 }
 
 func (l *FixedIntervalLimiter) CheckWait() {
 	l.mu.Lock()
-	next := l.last.Add(l.interval)
-	t := time.Now()
-	if !t.Before(next) {
-		l.last = t
-	} else {
-		time.Sleep(next.Sub(t))
-		l.last = time.Now()
+	l.configMu.Lock()
+	interval := l.interval
+	l.configMu.Unlock()
+	next := l.lastPermitted().Add(interval)
+	t := l.clock.Now()
+	if t.Before(next) {
+		l.clock.Sleep(next.Sub(t))
+		t = l.clock.Now()
 	}
+	l.stateMu.Lock()
+	l.last = t
+	l.stateMu.Unlock()
 	l.mu.Unlock()
 }
+
+// lastPermitted returns the time of the last permitted action. It is guarded by stateMu rather than mu, which is held while callers wait, so that encoding never waits.
+func (l *FixedIntervalLimiter) lastPermitted() time.Time {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	return l.last
+}
+
+func (l *FixedIntervalLimiter) Invoke(f func() error) (err error) {
+	l.CheckWait()
+	return l.panicPolicy.invoke(f, nil)
+}
+
+/*
+MarshalBinary encodes the time of the last permitted action, satisfying the encoding.BinaryMarshaler interface. It does not wait for callers waiting in CheckWait.
+*/
+func (l *FixedIntervalLimiter) MarshalBinary() ([]byte, error) {
+	e := newStateEncoder(fixedIntervalState)
+	e.time(l.lastPermitted())
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores the time of the last permitted action encoded by MarshalBinary, so the interval is enforced across a restart.
+*/
+func (l *FixedIntervalLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, fixedIntervalState)
+	last := d.time()
+	if err = d.finish(); err != nil {
+		return
+	}
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	l.last = last
+	return
+}
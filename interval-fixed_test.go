@@ -7,27 +7,28 @@ import (
 )
 
 func TestFixedIntervalLimiter(t *testing.T) {
-	l := NewFixedIntervalLimiter(time.Millisecond * 10)
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewFixedIntervalLimiter(time.Millisecond*10, WithClock(c))
 
 	var wg sync.WaitGroup
 	wg.Add(4)
-	start := time.Now()
+	start := c.Now()
 	for i := 0; i < 4; i += 1 {
 		go func() {
 			l.CheckWait()
 			wg.Done()
 		}()
 	}
-	wg.Wait()
-	duration := time.Now().Sub(start)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
 
-	expectedMin := time.Duration(30) * time.Millisecond
-	if duration < expectedMin {
-		t.Fatalf("Expected duration greater than %d, got %d", expectedMin, duration)
-	}
-
-	expectedMax := time.Duration(50) * time.Millisecond
-	if expectedMax < duration {
-		t.Fatalf("Expected duration less than %d, got %d", expectedMax, duration)
+	expected := time.Duration(30) * time.Millisecond
+	if duration != expected {
+		t.Fatalf("Expected duration %d, got %d", expected, duration)
 	}
 }
@@ -6,46 +6,141 @@ import (
 )
 
 type IntervalLimiter struct {
-	mu       sync.Mutex
-	interval time.Duration
-	recheck  time.Duration
-	last     time.Time
+	mu          sync.Mutex
+	clock       Clock
+	stateMu     sync.Mutex
+	last        time.Time
+	configMu    sync.Mutex
+	interval    time.Duration
+	recheck     time.Duration
+	panicPolicy PanicPolicy
 }
 
-func NewIntervalLimiter(interval time.Duration) *IntervalLimiter {
+func NewIntervalLimiter(interval time.Duration, opts ...Option) *IntervalLimiter {
+	// the interval is not validated, for compatibility with earlier versions
+	o := newOptions(opts)
+	must(firstError(o.validateApplicable("WithRecheck"), o.validateClock(), o.validateSet()))
+	return newIntervalLimiter(interval, o)
+}
+
+func BuildIntervalLimiter(opts ...Option) (l *IntervalLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithInterval", "WithRecheck"), o.validateClock(), o.validateInterval()); err != nil {
+		return
+	}
+	l = newIntervalLimiter(o.interval, o)
+	return
+}
+
+func newIntervalLimiter(interval time.Duration, o options) *IntervalLimiter {
 	return &IntervalLimiter{
-		interval: interval,
-		recheck:  interval * 2,
+		clock:       o.clock,
+		interval:    interval,
+		recheck:     o.recheck,
+		panicPolicy: o.panicPolicy,
 	}
 }
 
 func (l *IntervalLimiter) SetInterval(d time.Duration) {
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
 	l.interval = d
 }
 
 func (l *IntervalLimiter) SetRecheck(d time.Duration) {
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
 	l.recheck = d
 }
 
+/*
+Reconfigure applies the WithInterval and WithRecheck options to this limiter, preserving the time of the last permitted action. Callers already waiting observe the new interval at their next recheck. An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *IntervalLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithInterval", "WithRecheck"), o.validateSet()); err != nil {
+		return
+	}
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	if o.intervalSet {
+		l.interval = o.interval
+	}
+	if o.recheckSet {
+		l.recheck = o.recheck
+	}
+	return
+}
+
 func (l *IntervalLimiter) CheckWait() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	var t time.Time
 	for {
-		next := l.last.Add(l.interval)
-		t = time.Now()
+		interval, recheck := l.config()
+		next := l.lastPermitted().Add(interval)
+		t = l.clock.Now()
 		if !t.Before(next) {
 			break
 		}
-		sleepMin(l.recheck, next.Sub(t))
+		sleepMin(l.clock, recheck, next.Sub(t))
 	}
+	l.stateMu.Lock()
 	l.last = t
+	l.stateMu.Unlock()
+}
+
+func (l *IntervalLimiter) Invoke(f func() error) (err error) {
+	l.CheckWait()
+	return l.panicPolicy.invoke(f, nil)
+}
+
+// lastPermitted returns the time of the last permitted action. It is guarded by stateMu rather than mu, which is held while callers wait, so that encoding never waits.
+func (l *IntervalLimiter) lastPermitted() time.Time {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	return l.last
+}
+
+// config returns the current interval and recheck durations, where a recheck of zero is twice the interval.
+func (l *IntervalLimiter) config() (interval, recheck time.Duration) {
+	l.configMu.Lock()
+	defer l.configMu.Unlock()
+	interval, recheck = l.interval, l.recheck
+	if recheck <= 0 {
+		recheck = interval * 2
+	}
+	return
 }
 
-func sleepMin(a, b time.Duration) {
+func sleepMin(c Clock, a, b time.Duration) {
 	if a <= b {
-		time.Sleep(a)
+		c.Sleep(a)
 	} else {
-		time.Sleep(b)
+		c.Sleep(b)
+	}
+}
+
+/*
+MarshalBinary encodes the time of the last permitted action, satisfying the encoding.BinaryMarshaler interface. It does not wait for callers waiting in CheckWait.
+*/
+func (l *IntervalLimiter) MarshalBinary() ([]byte, error) {
+	e := newStateEncoder(intervalState)
+	e.time(l.lastPermitted())
+	return e.b, nil
+}
+
+/*
+UnmarshalBinary restores the time of the last permitted action encoded by MarshalBinary, so the interval is enforced across a restart. Callers already waiting observe it at their next recheck.
+*/
+func (l *IntervalLimiter) UnmarshalBinary(b []byte) (err error) {
+	d := newStateDecoder(b, intervalState)
+	last := d.time()
+	if err = d.finish(); err != nil {
+		return
 	}
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	l.last = last
+	return
 }
@@ -7,27 +7,51 @@ import (
 )
 
 func TestIntervalLimiter(t *testing.T) {
-	l := NewIntervalLimiter(time.Millisecond * 10)
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewIntervalLimiter(time.Millisecond*10, WithClock(c))
 
 	var wg sync.WaitGroup
 	wg.Add(4)
-	start := time.Now()
+	start := c.Now()
 	for i := 0; i < 4; i += 1 {
 		go func() {
 			l.CheckWait()
 			wg.Done()
 		}()
 	}
-	wg.Wait()
-	duration := time.Now().Sub(start)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
 
-	expectedMin := time.Duration(30) * time.Millisecond
-	if duration < expectedMin {
-		t.Fatalf("Expected duration greater than %d, got %d", expectedMin, duration)
+	expected := time.Duration(30) * time.Millisecond
+	if duration != expected {
+		t.Fatalf("Expected duration %d, got %d", expected, duration)
 	}
+}
+
+func TestIntervalLimiter_ReconfigureZeroRecheck(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewIntervalLimiter(time.Second, WithClock(c), WithRecheck(100*time.Millisecond))
 
-	expectedMax := time.Duration(50) * time.Millisecond
-	if expectedMax < duration {
-		t.Fatalf("Expected duration less than %d, got %d", expectedMax, duration)
+	// a zero recheck restores the default rather than rechecking without sleeping
+	if err := l.Reconfigure(WithInterval(2*time.Second), WithRecheck(0)); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
 	}
+	if _, recheck := l.config(); recheck != 4*time.Second {
+		t.Errorf("Expected %s, got %s", 4*time.Second, recheck)
+	}
+
+	l.CheckWait()
+	done := make(chan struct{})
+	go func() {
+		l.CheckWait()
+		close(done)
+	}()
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+	<-done
 }
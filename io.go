@@ -0,0 +1,138 @@
+package limiter
+
+import (
+	"context"
+	"io"
+)
+
+/*
+Reader limits the rate at which bytes are read from an underlying io.Reader, using a BucketRateLimiter which may be shared with other Readers and Writers to enforce a combined budget.
+*/
+type Reader struct {
+	r       io.Reader
+	limiter *BucketRateLimiter
+	ctx     context.Context
+}
+
+/*
+NewReader instantiates a new Reader which reads from the provided io.Reader at no more than the provided rate of bytes, with a burst size equal to the rate's count.
+*/
+func NewReader(r io.Reader, rate Rate, opts ...Option) *Reader {
+	return NewReaderWithLimiter(r, NewBucketRateLimiter(rate, 0, opts...))
+}
+
+/*
+NewReaderWithLimiter instantiates a new Reader which reads from the provided io.Reader within the budget of the provided BucketRateLimiter.
+*/
+func NewReaderWithLimiter(r io.Reader, l *BucketRateLimiter) *Reader {
+	return &Reader{
+		r:       r,
+		limiter: l,
+		ctx:     context.Background(),
+	}
+}
+
+/*
+WithContext returns a shallow copy of the Reader which stops waiting on the limiter and returns the context's error when the context is done.
+*/
+func (r *Reader) WithContext(ctx context.Context) *Reader {
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+/*
+Limiter returns the BucketRateLimiter whose budget the Reader consumes.
+*/
+func (r *Reader) Limiter() *BucketRateLimiter {
+	return r.limiter
+}
+
+/*
+Read reads up to len(p) bytes from the underlying io.Reader and then waits until the bytes read are within the limiter's budget.
+
+If the Reader's context is done while waiting, the bytes already read are returned along with the context's error. They remain consumed from the budget, since they have already been transferred.
+*/
+func (r *Reader) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return
+	}
+	if len(p) > rateChunkSize {
+		p = p[:rateChunkSize]
+	}
+	n, err = r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.waitReserved(r.ctx, r.limiter.reserve(n)); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return
+}
+
+/*
+Writer limits the rate at which bytes are written to an underlying io.Writer, using a BucketRateLimiter which may be shared with other Readers and Writers to enforce a combined budget.
+*/
+type Writer struct {
+	w       io.Writer
+	limiter *BucketRateLimiter
+	ctx     context.Context
+}
+
+/*
+NewWriter instantiates a new Writer which writes to the provided io.Writer at no more than the provided rate of bytes, with a burst size equal to the rate's count.
+*/
+func NewWriter(w io.Writer, rate Rate, opts ...Option) *Writer {
+	return NewWriterWithLimiter(w, NewBucketRateLimiter(rate, 0, opts...))
+}
+
+/*
+NewWriterWithLimiter instantiates a new Writer which writes to the provided io.Writer within the budget of the provided BucketRateLimiter.
+*/
+func NewWriterWithLimiter(w io.Writer, l *BucketRateLimiter) *Writer {
+	return &Writer{
+		w:       w,
+		limiter: l,
+		ctx:     context.Background(),
+	}
+}
+
+/*
+WithContext returns a shallow copy of the Writer which stops waiting on the limiter and returns the context's error when the context is done.
+*/
+func (w *Writer) WithContext(ctx context.Context) *Writer {
+	w2 := *w
+	w2.ctx = ctx
+	return &w2
+}
+
+/*
+Limiter returns the BucketRateLimiter whose budget the Writer consumes.
+*/
+func (w *Writer) Limiter() *BucketRateLimiter {
+	return w.limiter
+}
+
+/*
+Write writes p to the underlying io.Writer in chunks, waiting until each chunk is within the limiter's budget before writing it.
+
+If the Writer's context is done while waiting, the number of bytes already written is returned along with the context's error.
+*/
+func (w *Writer) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > rateChunkSize {
+			chunk = chunk[:rateChunkSize]
+		}
+		if err = w.limiter.WaitN(w.ctx, len(chunk)); err != nil {
+			return
+		}
+		var written int
+		written, err = w.w.Write(chunk)
+		n += written
+		if err != nil {
+			return
+		}
+		p = p[written:]
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewReader(bytes.NewReader(make([]byte, 50)), NewRate(1000, time.Second), WithClock(c))
+	r.Limiter().SetBurst(10)
+
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		n, err := io.Copy(io.Discard, r)
+		if err != nil {
+			t.Error(err)
+		}
+		if n != 50 {
+			t.Errorf("Expected 50, got %d", n)
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
+
+	expected := time.Duration(40) * time.Millisecond
+	if duration != expected {
+		t.Errorf("Expected duration %d, got %d", expected, duration)
+	}
+}
+
+func TestWriter_Shared(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBucketRateLimiter(NewRate(1000, time.Second), 10, WithClock(c))
+	var a, b bytes.Buffer
+	wa := NewWriterWithLimiter(&a, l)
+	wb := NewWriterWithLimiter(&b, l)
+
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		wa.Write(make([]byte, 30))
+		wb.Write(make([]byte, 30))
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
+
+	expected := time.Duration(50) * time.Millisecond
+	if duration != expected {
+		t.Errorf("Expected duration %d, got %d", expected, duration)
+	}
+	if a.Len() != 30 || b.Len() != 30 {
+		t.Errorf("Expected 30 and 30, got %d and %d", a.Len(), b.Len())
+	}
+}
+
+func TestWriter_Context(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	var buf bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewWriter(&buf, NewRate(10, time.Second), WithClock(c)).WithContext(ctx)
+
+	go func() {
+		c.BlockUntil(1)
+		cancel()
+	}()
+	n, err := w.Write(make([]byte, 20))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0, got %d", n)
+	}
+
+	// the canceled quantity is returned to the budget
+	if d := w.Limiter().reserve(10); d > 0 {
+		t.Errorf("Expected budget to be restored, got wait of %d", d)
+	}
+}
+
+func TestReader_Context(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewReader(bytes.NewReader(make([]byte, 20)), NewRate(10, time.Second), WithClock(c)).WithContext(ctx)
+
+	go func() {
+		c.BlockUntil(1)
+		cancel()
+	}()
+	n, err := r.Read(make([]byte, 20))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled, got: %v", err)
+	}
+	if n != 20 {
+		t.Errorf("Expected 20, got %d", n)
+	}
+
+	// the bytes were read, so they are not returned to the budget
+	if d := r.Limiter().reserve(10); d != 2*time.Second {
+		t.Errorf("Expected wait of %d, got %d", 2*time.Second, d)
+	}
+}
@@ -0,0 +1,153 @@
+package limiter
+
+import (
+	"container/list"
+	"sync"
+)
+
+/*
+DefaultMaxKeys is the number of keys for which a KeyedLimiter retains limiters unless SetMaxKeys is called.
+*/
+const DefaultMaxKeys = 10000
+
+/*
+KeyedLimiter maintains an InvocationLimiter per key, such as per tenant or per remote host, creating each one when its key is first used. It satisfies the InvocationLimiter interface using the empty key.
+
+Keys may be controlled by clients, so the limiters of at most the number of keys set by SetMaxKeys are retained; when a new key would exceed it, the least recently used key's limiter is discarded, and is created anew if that key is used again.
+*/
+type KeyedLimiter struct {
+	mu         sync.Mutex
+	newLimiter func(key string) (il InvocationLimiter, retain bool)
+	maxKeys    int
+	keys       map[string]*list.Element
+	recent     *list.List
+}
+
+// keyedLimiter is an element of the recently used keys list.
+type keyedLimiter struct {
+	key     string
+	limiter InvocationLimiter
+}
+
+/*
+NewKeyedLimiter instantiates a new KeyedLimiter which calls the provided function to create the limiter for each new key. The function may return the same limiter for several keys to share limits between them.
+
+The function is not called with the KeyedLimiter's lock held, so a slow function only delays callers of keys which are not yet retained. Concurrent first uses of a key may call it more than once, in which case one of the results is retained.
+*/
+func NewKeyedLimiter(newLimiter func(key string) InvocationLimiter) (l *KeyedLimiter) {
+	return newKeyedLimiter(func(key string) (InvocationLimiter, bool) {
+		return newLimiter(key), true
+	})
+}
+
+// newKeyedLimiter instantiates a new KeyedLimiter whose function also reports whether the limiter is retained for the key; limiters shared by any number of keys need not be.
+func newKeyedLimiter(newLimiter func(key string) (il InvocationLimiter, retain bool)) (l *KeyedLimiter) {
+	l = &KeyedLimiter{
+		newLimiter: newLimiter,
+		maxKeys:    DefaultMaxKeys,
+		keys:       make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+	return
+}
+
+/*
+SetMaxKeys sets the number of keys for which limiters are retained, discarding the least recently used keys' limiters if there are more. A value less than 1 restores DefaultMaxKeys.
+*/
+func (l *KeyedLimiter) SetMaxKeys(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n < 1 {
+		n = DefaultMaxKeys
+	}
+	l.maxKeys = n
+	l.evictKeys()
+}
+
+/*
+Get returns the limiter for the provided key, creating it if necessary.
+*/
+func (l *KeyedLimiter) Get(key string) InvocationLimiter {
+	l.mu.Lock()
+	if e, ok := l.keys[key]; ok {
+		l.recent.MoveToFront(e)
+		l.mu.Unlock()
+		return e.Value.(*keyedLimiter).limiter
+	}
+	l.mu.Unlock()
+
+	il, retain := l.newLimiter(key)
+	if !retain {
+		return il
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.keys[key]; ok {
+		l.recent.MoveToFront(e)
+		return e.Value.(*keyedLimiter).limiter
+	}
+	l.keys[key] = l.recent.PushFront(&keyedLimiter{key, il})
+	l.evictKeys()
+	return il
+}
+
+/*
+Range calls f for each key whose limiter is retained, until f returns false.
+*/
+func (l *KeyedLimiter) Range(f func(key string, il InvocationLimiter) bool) {
+	l.mu.Lock()
+	snapshot := make([]keyedLimiter, 0, len(l.keys))
+	for e := l.recent.Front(); e != nil; e = e.Next() {
+		snapshot = append(snapshot, *e.Value.(*keyedLimiter))
+	}
+	l.mu.Unlock()
+	for _, kl := range snapshot {
+		if !f(kl.key, kl.limiter) {
+			return
+		}
+	}
+}
+
+/*
+InvokeKey enforces the limits of the provided key's limiter around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *KeyedLimiter) InvokeKey(key string, f func() error) error {
+	return l.Get(key).Invoke(f)
+}
+
+/*
+Invoke enforces the limits of the empty key's limiter around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *KeyedLimiter) Invoke(f func() error) error {
+	return l.InvokeKey("", f)
+}
+
+func (l *KeyedLimiter) set(key string, il InvocationLimiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.keys[key]; ok {
+		e.Value.(*keyedLimiter).limiter = il
+		l.recent.MoveToFront(e)
+		return
+	}
+	l.keys[key] = l.recent.PushFront(&keyedLimiter{key, il})
+	l.evictKeys()
+}
+
+func (l *KeyedLimiter) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.keys[key]; ok {
+		l.recent.Remove(e)
+		delete(l.keys, key)
+	}
+}
+
+// evictKeys discards the least recently used keys' limiters until no more than maxKeys remain. The caller must hold mu.
+func (l *KeyedLimiter) evictKeys() {
+	for len(l.keys) > l.maxKeys {
+		e := l.recent.Back()
+		l.recent.Remove(e)
+		delete(l.keys, e.Value.(*keyedLimiter).key)
+	}
+}
@@ -0,0 +1,108 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	created := 0
+	l := NewKeyedLimiter(func(key string) InvocationLimiter {
+		created += 1
+		return NewTokenChanLimiter(1)
+	})
+
+	a := l.Get("a")
+	if l.Get("a") != a {
+		t.Error("Expected the same limiter for the same key")
+	}
+	if l.Get("b") == a {
+		t.Error("Expected distinct limiters for distinct keys")
+	}
+	if created != 2 {
+		t.Errorf("Expected 2, got %d", created)
+	}
+
+	keys := 0
+	l.Range(func(string, InvocationLimiter) bool {
+		keys += 1
+		return true
+	})
+	if keys != 2 {
+		t.Errorf("Expected 2, got %d", keys)
+	}
+}
+
+func TestKeyedLimiter_Invoke(t *testing.T) {
+	l := NewKeyedLimiter(func(key string) InvocationLimiter {
+		return NewTokenChanLimiter(1)
+	})
+
+	if err := l.InvokeKey("a", func() error { return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if err := l.Invoke(func() error { return nil }); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestKeyedLimiter_MaxKeys(t *testing.T) {
+	created := 0
+	l := NewKeyedLimiter(func(key string) InvocationLimiter {
+		created += 1
+		return NewTokenChanLimiter(1)
+	})
+	l.SetMaxKeys(2)
+
+	a := l.Get("a")
+	l.Get("b")
+	l.Get("a")
+	// "b" is the least recently used key, so it is discarded
+	l.Get("c")
+	if l.Get("a") != a {
+		t.Error("Expected recently used key to be retained")
+	}
+	l.Get("b")
+	if created != 4 {
+		t.Errorf("Expected '%d' limiters created, got '%d'", 4, created)
+	}
+	keys := 0
+	l.Range(func(string, InvocationLimiter) bool {
+		keys += 1
+		return true
+	})
+	if keys != 2 {
+		t.Errorf("Expected '%d' keys, got '%d'", 2, keys)
+	}
+}
+
+func TestKeyedLimiter_SlowCreate(t *testing.T) {
+	release := make(chan struct{})
+	l := NewKeyedLimiter(func(key string) InvocationLimiter {
+		if key == "slow" {
+			<-release
+		}
+		return NewTokenChanLimiter(1)
+	})
+	a := l.Get("a")
+	go l.Get("slow")
+	defer close(release)
+
+	// a slow creation does not delay other keys
+	done := make(chan struct{})
+	go func() {
+		l.Get("a")
+		l.Get("b")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected lookups not to wait for a slow creation")
+	}
+	if l.Get("a") != a {
+		t.Error("Expected the same limiter for the same key")
+	}
+}
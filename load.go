@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+LoadTarget is a Signal and the maximum value a LoadController keeps it below.
+*/
+type LoadTarget struct {
+	Signal Signal
+	Max    float64
+}
+
+var ErrInvalidLoadRange = errors.New("Load controller floor must not exceed its maximum.")
+
+// loadDecreaseFactor is the factor by which a LoadController's level is multiplied when a target is exceeded.
+const loadDecreaseFactor = 0.75
+
+// loadIncreaseSteps is the number of increases a LoadController takes to move from its floor to its maximum.
+const loadIncreaseSteps = 10
+
+/*
+LoadController adjusts a limiter's token count or rate to keep runtime signals, such as goroutine count, heap size, GC pauses and CPU utilisation, below their targets.
+
+Each adjustment samples every target's Signal. If any signal exceeds its target, the level is reduced by a quarter, down to the floor. If all signals are within their targets, the level is increased by a tenth of the range between the floor and the maximum (at least 1), up to the maximum. This additive-increase, multiplicative-decrease approach backs off quickly under load and recovers gradually.
+*/
+type LoadController struct {
+	mu      sync.Mutex
+	applyMu sync.Mutex
+	clock   Clock
+	targets []LoadTarget
+	floor   float64
+	max     float64
+	level   float64
+	apply   func(level float64)
+	poll    poller
+}
+
+/*
+NewTokenLoadController instantiates a new LoadController which adjusts the token count of the provided AdjustableTokenChanLimiter between the floor and the maximum. The level starts at the maximum.
+
+Lowering the token count never blocks an adjustment: tokens in use above the new count are retired as they are released.
+
+It panics if the arguments or options are invalid; BuildTokenLoadController returns the error instead.
+*/
+func NewTokenLoadController(l *AdjustableTokenChanLimiter, floor uint, max uint, targets []LoadTarget, opts ...Option) (c *LoadController) {
+	c, err := BuildTokenLoadController(l, floor, max, targets, opts...)
+	must(err)
+	return
+}
+
+/*
+BuildTokenLoadController instantiates a new LoadController as NewTokenLoadController does, or returns an error if the floor exceeds the maximum, the maximum exceeds the limiter's maximum token count, or an option is invalid.
+*/
+func BuildTokenLoadController(l *AdjustableTokenChanLimiter, floor uint, max uint, targets []LoadTarget, opts ...Option) (c *LoadController, err error) {
+	if l != nil && max > l.maxTokenCount {
+		err = ErrTokenCountExceedsMax
+		return
+	}
+	return buildLoadController(float64(floor), float64(max), targets, func(level float64) {
+		l.SetTokenCount(uint(math.Round(level)))
+	}, opts)
+}
+
+/*
+NewRateLoadController instantiates a new LoadController which adjusts the rate of the provided RateSetter, such as a BurstRateLimiter or BucketRateLimiter, between the floor and the maximum rate. The level starts at the maximum.
+
+The floor is converted to a count over the maximum rate's duration, and every adjusted rate uses that duration.
+
+It panics if the arguments or options are invalid; BuildRateLoadController returns the error instead.
+*/
+func NewRateLoadController(s RateSetter, floor Rate, max Rate, targets []LoadTarget, opts ...Option) (c *LoadController) {
+	c, err := BuildRateLoadController(s, floor, max, targets, opts...)
+	must(err)
+	return
+}
+
+/*
+BuildRateLoadController instantiates a new LoadController as NewRateLoadController does, or returns an error if the rates are invalid, the floor exceeds the maximum, or an option is invalid.
+*/
+func BuildRateLoadController(s RateSetter, floor Rate, max Rate, targets []LoadTarget, opts ...Option) (c *LoadController, err error) {
+	if err = firstError(floor.Validate(), max.Validate()); err != nil {
+		return
+	}
+	return buildLoadController(floor.Per(max.Duration), float64(max.Count), targets, func(level float64) {
+		count := int(math.Round(level))
+		if count < 1 {
+			count = 1
+		}
+		s.SetMaxRate(NewRate(count, max.Duration))
+	}, opts)
+}
+
+func buildLoadController(floor, max float64, targets []LoadTarget, apply func(float64), opts []Option) (c *LoadController, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable(), o.validateClock()); err != nil {
+		return
+	}
+	if floor > max {
+		err = ErrInvalidLoadRange
+		return
+	}
+	c = &LoadController{
+		clock:   o.clock,
+		targets: targets,
+		floor:   floor,
+		max:     max,
+		level:   max,
+		apply:   apply,
+	}
+	return
+}
+
+/*
+SetErrorHandler sets a function which is called with errors from sampling signals during periodic adjustments.
+*/
+func (c *LoadController) SetErrorHandler(f func(error)) {
+	c.poll.setErrorHandler(f)
+}
+
+/*
+Level returns the current token count or rate count.
+*/
+func (c *LoadController) Level() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.level
+}
+
+/*
+Adjust samples every target's Signal once and adjusts the level accordingly.
+
+Signals which fail to sample are ignored, and their errors are joined and returned. If no signal can be sampled, the level is unchanged.
+*/
+func (c *LoadController) Adjust() (err error) {
+	var errs []error
+	sampled, exceeded := false, false
+	for _, t := range c.targets {
+		v, sErr := t.Signal.Sample()
+		if sErr != nil {
+			errs = append(errs, sErr)
+			continue
+		}
+		sampled = true
+		exceeded = exceeded || v > t.Max
+	}
+	err = errors.Join(errs...)
+	if !sampled {
+		return
+	}
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+	c.mu.Lock()
+	prev := c.level
+	if exceeded {
+		c.level = math.Max(c.floor, c.level*loadDecreaseFactor)
+	} else {
+		c.level = math.Min(c.max, c.level+math.Max(1, (c.max-c.floor)/loadIncreaseSteps))
+	}
+	level := c.level
+	c.mu.Unlock()
+	if level != prev {
+		c.apply(level)
+	}
+	return
+}
+
+/*
+Start begins adjusting at the provided interval in a new goroutine. Calling Start on a controller which is already adjusting has no effect. ErrInvalidInterval is returned if the interval is not positive.
+*/
+func (c *LoadController) Start(interval time.Duration) error {
+	return c.poll.start(c.clock, interval, c.Adjust)
+}
+
+/*
+Stop ends periodic adjustment and waits for any adjustment in progress to complete. The current level is retained.
+*/
+func (c *LoadController) Stop() {
+	c.poll.halt()
+}
@@ -0,0 +1,148 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSignal struct {
+	mu    sync.Mutex
+	value float64
+	err   error
+}
+
+func (s *testSignal) set(v float64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value, s.err = v, err
+}
+
+func (s *testSignal) Sample() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, s.err
+}
+
+func TestLoadController(t *testing.T) {
+	goroutines, heap := &testSignal{}, &testSignal{}
+	tl := NewAdjustableTokenChanLimiter(20, 20)
+	c := NewTokenLoadController(tl, 2, 20, []LoadTarget{{goroutines, 100}, {heap, 1 << 20}})
+
+	goroutines.set(200, nil)
+	expected := []uint{15, 11, 8, 6, 5, 4, 3, 2, 2}
+	for _, n := range expected {
+		c.Adjust()
+		if count := tl.GetTokenCount(); count != n {
+			t.Fatalf("Expected %d tokens, got %d", n, count)
+		}
+	}
+
+	goroutines.set(50, nil)
+	c.Adjust()
+	if count := tl.GetTokenCount(); count != 4 {
+		t.Errorf("Expected additive increase to 4 tokens, got %d", count)
+	}
+	for i := 0; i < 10; i += 1 {
+		c.Adjust()
+	}
+	if count := tl.GetTokenCount(); count != 20 {
+		t.Errorf("Expected 20 tokens, got %d", count)
+	}
+
+	errSample := errors.New("error")
+	heap.set(0, errSample)
+	goroutines.set(200, nil)
+	if err := c.Adjust(); !errors.Is(err, errSample) {
+		t.Errorf("Expected '%v', got '%v'", errSample, err)
+	}
+	if level := c.Level(); level != 15 {
+		t.Errorf("Expected remaining signals to be used, got level %f", level)
+	}
+	goroutines.set(0, errSample)
+	c.Adjust()
+	if level := c.Level(); level != 15 {
+		t.Errorf("Expected unchanged level without samples, got %f", level)
+	}
+}
+
+func TestLoadController_Periodic(t *testing.T) {
+	clock := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	cpu := &testSignal{}
+	bl := NewBurstRateLimiter(NewRate(100, time.Second), WithClock(clock))
+	c := NewRateLoadController(bl, NewRate(1, 100*time.Millisecond), NewRate(100, time.Second), []LoadTarget{{cpu, 0.8}}, WithClock(clock))
+	errs := make(chan error, 1)
+	c.SetErrorHandler(func(err error) { errs <- err })
+
+	cpu.set(0.9, nil)
+	c.Start(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	c.Stop()
+	if bl.maxCount != 75 {
+		t.Errorf("Expected 75, got %d", bl.maxCount)
+	}
+
+	cpu.set(0, ErrSignalUnavailable)
+	c.Start(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errs; !errors.Is(err, ErrSignalUnavailable) {
+		t.Errorf("Expected '%v', got '%v'", ErrSignalUnavailable, err)
+	}
+	c.Stop()
+	if clock.Sleepers() != 0 {
+		t.Errorf("Expected no pending timers, got %d", clock.Sleepers())
+	}
+}
+
+func TestLoadController_DecreaseWhileTokensHeld(t *testing.T) {
+	load := &testSignal{}
+	tl := NewAdjustableTokenChanLimiter(4, 4)
+	c := NewTokenLoadController(tl, 1, 4, []LoadTarget{{load, 1}})
+	held := make([]*[16]byte, 4)
+	for i := range held {
+		held[i] = tl.AcquireToken()
+	}
+
+	// every token is in use, but lowering the limit must not wait for them
+	load.set(2, nil)
+	done := make(chan struct{})
+	go func() {
+		c.Adjust()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Adjust to return while tokens are held")
+	}
+	if n := tl.GetTokenCount(); n != 3 {
+		t.Errorf("Expected 3, got %d", n)
+	}
+	if n := tl.PendingShrink(); n != 1 {
+		t.Errorf("Expected 1 token awaiting retirement, got %d", n)
+	}
+	tl.ReleaseToken(held[0])
+	if n := tl.PendingShrink(); n != 0 {
+		t.Errorf("Expected released token to be retired, got %d pending", n)
+	}
+}
+
+func TestBuildLoadController_Invalid(t *testing.T) {
+	if _, err := BuildTokenLoadController(nil, 5, 2, nil); err != ErrInvalidLoadRange {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidLoadRange, err)
+	}
+	if _, err := BuildRateLoadController(nil, NewRate(5, time.Second), NewRate(2, time.Second), nil); err != ErrInvalidLoadRange {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidLoadRange, err)
+	}
+	if _, err := BuildTokenLoadController(NewAdjustableTokenChanLimiter(2, 2), 1, 3, nil); err != ErrTokenCountExceedsMax {
+		t.Errorf("Expected '%v', got '%v'", ErrTokenCountExceedsMax, err)
+	}
+	c := NewTokenLoadController(nil, 1, 2, nil)
+	if err := c.Start(0); err != ErrInvalidInterval {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidInterval, err)
+	}
+}
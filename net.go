@@ -0,0 +1,133 @@
+package limiter
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+)
+
+// rateChunkSize bounds the quantity consumed from a WeightedRateLimiter by a single read or write, to smooth throughput.
+const rateChunkSize = 32 * 1024
+
+/*
+NewTokenListener wraps the provided net.Listener so that each accepted connection holds a token from the provided TokenLimiter, limiting the number of concurrently open connections.
+
+Accept blocks until a token can be acquired, and the token is released when the accepted connection is closed. Closing the listener ends the wait, and Accept returns net.ErrClosed.
+*/
+func NewTokenListener(ln net.Listener, l TokenLimiter) net.Listener {
+	closed, cancel := context.WithCancel(context.Background())
+	return &tokenListener{
+		Listener: ln,
+		limiter:  l,
+		closed:   closed,
+		cancel:   cancel,
+	}
+}
+
+type tokenListener struct {
+	net.Listener
+	limiter TokenLimiter
+	closed  context.Context
+	cancel  context.CancelFunc
+}
+
+func (ln *tokenListener) Accept() (c net.Conn, err error) {
+	token, err := AcquireTokenContext(ln.closed, ln.limiter)
+	if err != nil {
+		err = net.ErrClosed
+		return
+	}
+	if c, err = ln.Listener.Accept(); err != nil {
+		ln.limiter.ReleaseToken(token)
+		return
+	}
+	c = &tokenConn{
+		Conn:    c,
+		limiter: ln.limiter,
+		token:   token,
+	}
+	return
+}
+
+func (ln *tokenListener) Close() error {
+	ln.cancel()
+	return ln.Listener.Close()
+}
+
+type tokenConn struct {
+	net.Conn
+	once    sync.Once
+	limiter TokenLimiter
+	token   *[16]byte
+}
+
+func (c *tokenConn) Close() (err error) {
+	err = c.Conn.Close()
+	c.once.Do(func() {
+		c.limiter.ReleaseToken(c.token)
+	})
+	return
+}
+
+/*
+NewRateConn wraps the provided net.Conn so that bytes read and written are limited by the provided WeightedRateLimiters. Either limiter may be nil, and the same limiter may be used for both directions to limit combined throughput.
+*/
+func NewRateConn(c net.Conn, read, write WeightedRateLimiter) net.Conn {
+	return &rateConn{
+		Conn:  c,
+		read:  read,
+		write: write,
+	}
+}
+
+type rateConn struct {
+	net.Conn
+	read  WeightedRateLimiter
+	write WeightedRateLimiter
+}
+
+func (c *rateConn) Read(p []byte) (n int, err error) {
+	if c.read == nil {
+		return c.Conn.Read(p)
+	}
+	return limitRead(c.Conn, c.read, p)
+}
+
+func (c *rateConn) Write(p []byte) (n int, err error) {
+	if c.write == nil {
+		return c.Conn.Write(p)
+	}
+	return limitWrite(c.Conn, c.write, p)
+}
+
+// limitRead reads at most one chunk and then consumes the number of bytes read from the limiter's budget.
+func limitRead(r io.Reader, l WeightedRateLimiter, p []byte) (n int, err error) {
+	if len(p) > rateChunkSize {
+		p = p[:rateChunkSize]
+	}
+	n, err = r.Read(p)
+	if n > 0 {
+		l.CheckWaitN(n)
+	}
+	return
+}
+
+// limitWrite consumes each chunk from the limiter's budget before writing it.
+func limitWrite(w io.Writer, l WeightedRateLimiter, p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > rateChunkSize {
+			chunk = chunk[:rateChunkSize]
+		}
+		l.CheckWaitN(len(chunk))
+		var written int
+		written, err = w.Write(chunk)
+		n += written
+		if err != nil {
+			return
+		}
+		p = p[written:]
+	}
+	return
+}
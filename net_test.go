@@ -0,0 +1,118 @@
+package limiter
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTokenListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := NewTokenChanLimiter(1)
+	ln := NewTokenListener(inner, tl)
+	defer ln.Close()
+
+	for i := 0; i < 2; i += 1 {
+		client, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+	}
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tl.TryAcquireToken(); ok {
+		t.Fatal("Expected token to be held by connection")
+	}
+
+	accepted := make(chan net.Conn)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	select {
+	case <-accepted:
+		t.Fatal("Expected Accept to block while the token is held")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Close()
+	c.Close()
+	(<-accepted).Close()
+
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Fatal("Expected token to be released")
+	}
+	if _, ok := tl.TryAcquireToken(); ok {
+		t.Fatal("Expected a single token after repeated Close")
+	}
+}
+
+func TestTokenListener_Close(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := NewTokenChanLimiter(1)
+	token := tl.AcquireToken()
+	defer tl.ReleaseToken(token)
+	ln := NewTokenListener(inner, tl)
+
+	// Accept is waiting for the held token when the listener is closed
+	result := make(chan error)
+	go func() {
+		_, err := ln.Accept()
+		result <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ln.Close()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Expected '%v', got '%v'", net.ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Accept to return after Close")
+	}
+}
+
+func TestRateConn(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewBucketRateLimiter(NewRate(1000, time.Second), 10, WithClock(c))
+	rc := NewRateConn(a, nil, l)
+
+	go io.Copy(io.Discard, b)
+
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		n, err := rc.Write(make([]byte, 50))
+		if err != nil {
+			t.Error(err)
+		}
+		if n != 50 {
+			t.Errorf("Expected 50, got %d", n)
+		}
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Millisecond, done)
+	duration := c.Now().Sub(start)
+
+	expected := time.Duration(40) * time.Millisecond
+	if duration != expected {
+		t.Errorf("Expected duration %d, got %d", expected, duration)
+	}
+}
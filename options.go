@@ -0,0 +1,392 @@
+package limiter
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrMissingOption        = errors.New("Option is required.")
+	ErrInvalidRate          = errors.New("Rate count and duration must be greater than zero.")
+	ErrInvalidBurst         = errors.New("Burst size must not be negative.")
+	ErrInvalidTokenCount    = errors.New("Token count must be greater than zero.")
+	ErrTokenCountExceedsMax = errors.New("Token count must not exceed the maximum token count.")
+	ErrInvalidInterval      = errors.New("Interval must be greater than zero.")
+	ErrNilBackOffFunc       = errors.New("Backoff function must not be nil.")
+	ErrNilClock             = errors.New("Clock must not be nil.")
+	ErrNotAdjustable        = errors.New("Option cannot be changed after construction.")
+	ErrInapplicableOption   = errors.New("Option does not apply to this limiter.")
+)
+
+/*
+OptionError describes an option which is missing or invalid for the limiter being constructed or reconfigured. Err is one of the package's ErrInvalid, ErrNil, ErrMissingOption, ErrNotAdjustable or ErrInapplicableOption errors, and can be matched with errors.Is.
+*/
+type OptionError struct {
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return e.Option + ": " + e.Err.Error()
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+/*
+Option configures a limiter when passed to its constructor or Reconfigure method. WithClock and WithPanicPolicy are accepted everywhere, and are ignored by limiters which do not use them. Any other option which does not apply to the limiter is rejected with an *OptionError wrapping ErrInapplicableOption by Build functions and Reconfigure methods.
+*/
+type Option func(o *options)
+
+type options struct {
+	clock        Clock
+	rate         Rate
+	rateSet      bool
+	burst        int
+	burstSet     bool
+	tokens       uint
+	tokensSet    bool
+	maxTokens    uint
+	maxTokensSet bool
+	interval     time.Duration
+	intervalSet  bool
+	recheck      time.Duration
+	recheckSet   bool
+	backOffFunc  func(uint) uint
+	backOffSet   bool
+	quota        int
+	period       QuotaPeriod
+	quotaSet     bool
+	location     *time.Location
+	locationSet  bool
+	softLimit    int
+	onSoftLimit  func(QuotaUsage)
+	quotaStore   QuotaStore
+	rampShape    RampShape
+	key          string
+	panicPolicy  PanicPolicy
+	resources    map[string]uint
+	edges        Edge
+	maxWait      time.Duration
+	ordered      bool
+	applied      []string
+}
+
+/*
+WithClock sets the Clock used by a limiter. The default is RealClock.
+*/
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithClock")
+		o.clock = c
+	}
+}
+
+/*
+WithRate sets the maximum rate of a rate limiter.
+*/
+func WithRate(r Rate) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithRate")
+		o.rate = r
+		o.rateSet = true
+	}
+}
+
+/*
+WithBurst sets the burst size of a BucketRateLimiter. The default is the rate's count.
+*/
+func WithBurst(n int) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithBurst")
+		o.burst = n
+		o.burstSet = true
+	}
+}
+
+/*
+WithTokens sets the number of tokens of a token limiter, which is the initial number of tokens for an AdjustableTokenChanLimiter.
+*/
+func WithTokens(n uint) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithTokens")
+		o.tokens = n
+		o.tokensSet = true
+	}
+}
+
+/*
+WithMaxTokens sets the maximum number of tokens of an AdjustableTokenChanLimiter.
+*/
+func WithMaxTokens(n uint) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithMaxTokens")
+		o.maxTokens = n
+		o.maxTokensSet = true
+	}
+}
+
+/*
+WithInterval sets the minimum interval of an interval limiter.
+*/
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithInterval")
+		o.interval = d
+		o.intervalSet = true
+	}
+}
+
+/*
+WithRecheck sets the maximum duration an IntervalLimiter sleeps before rechecking its interval. The default is twice the interval, which is also used when the duration is zero, including on reconfiguration.
+*/
+func WithRecheck(d time.Duration) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithRecheck")
+		o.recheck = d
+		o.recheckSet = true
+	}
+}
+
+/*
+WithBackOff sets the backoff function of a fail limiter, or the wait-backoff function of a BurstRateLimiter.
+*/
+func WithBackOff(f func(uint) uint) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithBackOff")
+		o.backOffFunc = f
+		o.backOffSet = true
+	}
+}
+
+/*
+WithQuota sets the limit and reset period of a QuotaLimiter.
+*/
+func WithQuota(limit int, period QuotaPeriod) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithQuota")
+		o.quota = limit
+		o.period = period
+		o.quotaSet = true
+	}
+}
+
+/*
+WithLocation sets the time zone in which a QuotaLimiter's periods begin. The default is UTC.
+*/
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithLocation")
+		o.location = loc
+		o.locationSet = true
+	}
+}
+
+/*
+WithSoftLimit sets a usage level at which a QuotaLimiter calls the provided function, once per period, before the hard limit is reached.
+*/
+func WithSoftLimit(n int, f func(QuotaUsage)) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithSoftLimit")
+		o.softLimit = n
+		o.onSoftLimit = f
+	}
+}
+
+/*
+WithQuotaStore sets the QuotaStore which persists a QuotaLimiter's usage across restarts.
+*/
+func WithQuotaStore(s QuotaStore) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithQuotaStore")
+		o.quotaStore = s
+	}
+}
+
+/*
+WithRampShape sets the shape of a WarmUp's ramp. The default is LinearRamp.
+*/
+func WithRampShape(shape RampShape) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithRampShape")
+		o.rampShape = shape
+	}
+}
+
+/*
+WithKey sets the key of a Token acquired with Acquire or TryAcquire.
+*/
+func WithKey(key string) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithKey")
+		o.key = key
+	}
+}
+
+/*
+WithPanicPolicy sets what a limiter's Invoke method does when the invoked function panics. The default is RePanic.
+*/
+func WithPanicPolicy(p PanicPolicy) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithPanicPolicy")
+		o.panicPolicy = p
+	}
+}
+
+/*
+WithResources sets the capacities of a ResourceLimiter's pools, keyed by resource name.
+*/
+func WithResources(capacity map[string]uint) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithResources")
+		o.resources = capacity
+	}
+}
+
+/*
+WithEdges sets the edges on which an EventLimiter emits events. The default depends on the constructor.
+*/
+func WithEdges(e Edge) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithEdges")
+		o.edges = e
+	}
+}
+
+/*
+WithMaxWait sets the longest a debouncing EventLimiter delays emission during a continuous burst of events. The default is no limit.
+*/
+func WithMaxWait(d time.Duration) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithMaxWait")
+		o.maxWait = d
+	}
+}
+
+/*
+WithOrdered sets whether a ParallelMap stage delivers results in the order of their inputs. The default is false.
+*/
+func WithOrdered(ordered bool) Option {
+	return func(o *options) {
+		o.applied = append(o.applied, "WithOrdered")
+		o.ordered = ordered
+	}
+}
+
+func newOptions(opts []Option) (o options) {
+	o.clock = RealClock{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
+
+func (o *options) validateClock() error {
+	if o.clock == nil {
+		return &OptionError{"WithClock", ErrNilClock}
+	}
+	return nil
+}
+
+func (o *options) validateRate() error {
+	if !o.rateSet {
+		return &OptionError{"WithRate", ErrMissingOption}
+	}
+	if err := o.rate.Validate(); err != nil {
+		return &OptionError{"WithRate", err}
+	}
+	return nil
+}
+
+func (o *options) validateBackOff(required bool) error {
+	if !o.backOffSet {
+		if required {
+			return &OptionError{"WithBackOff", ErrMissingOption}
+		}
+		return nil
+	}
+	if o.backOffFunc == nil {
+		return &OptionError{"WithBackOff", ErrNilBackOffFunc}
+	}
+	return nil
+}
+
+// validateApplicable returns an *OptionError for the first option provided which is not one of the named options, WithClock or WithPanicPolicy.
+func (o *options) validateApplicable(names ...string) error {
+	for _, applied := range o.applied {
+		if applied == "WithClock" || applied == "WithPanicPolicy" {
+			continue
+		}
+		found := false
+		for _, name := range names {
+			found = found || name == applied
+		}
+		if !found {
+			return &OptionError{applied, ErrInapplicableOption}
+		}
+	}
+	return nil
+}
+
+// validateSet validates only the options which were provided, for reconfiguration.
+func (o *options) validateSet() error {
+	if o.rateSet {
+		if err := o.rate.Validate(); err != nil {
+			return &OptionError{"WithRate", err}
+		}
+	}
+	if o.burstSet && o.burst < 0 {
+		return &OptionError{"WithBurst", ErrInvalidBurst}
+	}
+	if o.intervalSet && o.interval <= 0 {
+		return &OptionError{"WithInterval", ErrInvalidInterval}
+	}
+	if o.recheckSet && o.recheck < 0 {
+		return &OptionError{"WithRecheck", ErrInvalidInterval}
+	}
+	return o.validateBackOff(false)
+}
+
+func (o *options) validateQuota() error {
+	if !o.quotaSet {
+		return &OptionError{"WithQuota", ErrMissingOption}
+	}
+	if o.quota <= 0 {
+		return &OptionError{"WithQuota", ErrInvalidQuota}
+	}
+	if err := o.period.validate(); err != nil {
+		return &OptionError{"WithQuota", err}
+	}
+	if o.locationSet && o.location == nil {
+		return &OptionError{"WithLocation", ErrNilLocation}
+	}
+	return nil
+}
+
+func (o *options) validateInterval() error {
+	if o.interval <= 0 {
+		return &OptionError{"WithInterval", ErrInvalidInterval}
+	}
+	if o.recheck < 0 {
+		return &OptionError{"WithRecheck", ErrInvalidInterval}
+	}
+	return nil
+}
+
+// firstError returns the first non-nil error.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// must panics with the provided error, for constructors which cannot return one.
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
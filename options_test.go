@@ -0,0 +1,114 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestBuild_Valid(t *testing.T) {
+	rate := WithRate(NewRate(10, time.Second))
+
+	if _, err := BuildBurstRateLimiter(rate); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := BuildBucketRateLimiter(rate, WithBurst(5)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := BuildFailBackOffLimiter(WithBackOff(backoff.None)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := BuildFailRateLimiter(rate, WithBackOff(backoff.None)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if l, err := BuildIntervalLimiter(WithInterval(time.Second)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	} else if _, recheck := l.config(); recheck != 2*time.Second {
+		t.Errorf("Expected %s, got %s", 2*time.Second, recheck)
+	}
+	if _, err := BuildFixedIntervalLimiter(WithInterval(time.Second)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if _, err := BuildTokenChanLimiter(WithTokens(1)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if l, err := BuildAdjustableTokenChanLimiter(WithMaxTokens(4)); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	} else if actual := l.GetTokenCount(); actual != 0 {
+		t.Errorf("Expected 0, got %d", actual)
+	}
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func() error
+		option string
+		err    error
+	}{
+		{"zero rate", func() error { _, err := BuildBurstRateLimiter(WithRate(NewRate(0, 0))); return err }, "WithRate", ErrInvalidRate},
+		{"missing rate", func() error { _, err := BuildBurstRateLimiter(); return err }, "WithRate", ErrMissingOption},
+		{"negative burst", func() error {
+			_, err := BuildBucketRateLimiter(WithRate(NewRate(1, time.Second)), WithBurst(-1))
+			return err
+		}, "WithBurst", ErrInvalidBurst},
+		{"nil backoff", func() error { _, err := BuildFailBackOffLimiter(WithBackOff(nil)); return err }, "WithBackOff", ErrNilBackOffFunc},
+		{"missing backoff", func() error {
+			_, err := BuildFailRateLimiter(WithRate(NewRate(1, time.Second)))
+			return err
+		}, "WithBackOff", ErrMissingOption},
+		{"zero interval", func() error { _, err := BuildIntervalLimiter(WithInterval(0)); return err }, "WithInterval", ErrInvalidInterval},
+		{"zero tokens", func() error { _, err := BuildTokenChanLimiter(WithTokens(0)); return err }, "WithTokens", ErrInvalidTokenCount},
+		{"tokens over max", func() error {
+			_, err := BuildAdjustableTokenChanLimiter(WithTokens(5), WithMaxTokens(2))
+			return err
+		}, "WithTokens", ErrTokenCountExceedsMax},
+		{"nil clock", func() error {
+			_, err := BuildFixedIntervalLimiter(WithInterval(time.Second), WithClock(nil))
+			return err
+		}, "WithClock", ErrNilClock},
+		{"inapplicable option", func() error {
+			_, err := BuildTokenChanLimiter(WithTokens(1), WithQuota(10, Daily))
+			return err
+		}, "WithQuota", ErrInapplicableOption},
+		{"inapplicable reconfigure option", func() error {
+			return NewBucketRateLimiter(NewRate(1, time.Second), 1).Reconfigure(WithInterval(time.Second))
+		}, "WithInterval", ErrInapplicableOption},
+	}
+	for _, test := range tests {
+		err := test.build()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected '%v', got '%v'", test.name, test.err, err)
+			continue
+		}
+		var oe *OptionError
+		if !errors.As(err, &oe) || oe.Option != test.option {
+			t.Errorf("%s: expected option %s, got '%v'", test.name, test.option, err)
+		}
+	}
+}
+
+func TestNew_AcceptsEarlierArguments(t *testing.T) {
+	// constructors which predate validation accept the arguments they always have
+	NewTokenChanLimiter(0)
+	if tl := NewAdjustableTokenChanLimiter(5, 2); tl.GetTokenCount() != 2 {
+		t.Errorf("Expected 2, got %d", tl.GetTokenCount())
+	}
+	NewFailBackOffLimiter(nil)
+	NewFailRateLimiter(NewRate(1, time.Second), nil)
+	NewBurstRateLimiter(Rate{})
+	NewIntervalLimiter(0)
+	NewFixedIntervalLimiter(0)
+}
+
+func TestNew_PanicsOnInvalidOption(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrInapplicableOption) {
+			t.Errorf("Expected '%v', got '%v'", ErrInapplicableOption, err)
+		}
+	}()
+	NewTokenChanLimiter(1, WithQuota(10, Daily))
+}
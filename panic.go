@@ -0,0 +1,69 @@
+package limiter
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+/*
+PanicPolicy determines what a limiter's Invoke method does when the invoked function panics. In every case, the limiter first releases any token held for the invocation and reports the invocation as a failure.
+*/
+type PanicPolicy int
+
+const (
+	// RePanic continues panicking with the original value after the limiter has cleaned up. It is the default.
+	RePanic PanicPolicy = iota
+	// ConvertPanic recovers the panic and returns it from Invoke as a *PanicError.
+	ConvertPanic
+)
+
+/*
+PanicError is returned by Invoke under the ConvertPanic policy when the invoked function panics. It holds the value passed to panic and the stack trace of the panicking goroutine.
+*/
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Invoked function panicked: %v", e.Value)
+}
+
+/*
+Unwrap returns the panic value if it is an error, so it can be matched with errors.Is and errors.As.
+*/
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// invoke calls f and then done, if it is not nil, with whether f returned a nil error. If f panics or exits its goroutine, done is called with false before the policy is applied.
+func (p PanicPolicy) invoke(f func() error, done func(success bool)) (err error) {
+	returned := false
+	defer func() {
+		if returned {
+			return
+		}
+		v := recover()
+		if done != nil {
+			done(false)
+		}
+		if v == nil {
+			// runtime.Goexit was called, which continues after deferred calls
+			return
+		}
+		if p == ConvertPanic {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+			return
+		}
+		panic(v)
+	}()
+	err = f()
+	returned = true
+	if done != nil {
+		done(err == nil)
+	}
+	return
+}
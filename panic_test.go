@@ -0,0 +1,92 @@
+package limiter
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestPanicPolicy_RePanic(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	l := NewTokenFailLimiter(NewTokenChanLimiter(1), fl)
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("Expected panic value 'boom', got '%v'", v)
+			}
+		}()
+		l.Invoke(func() error { panic("boom") })
+		t.Error("Expected panic, got none")
+	}()
+
+	if fl.failCount != 1 {
+		t.Errorf("Expected fail count '%d', got '%d'", 1, fl.failCount)
+	}
+	if token, ok := l.tokenLimiter.(*TokenChanLimiter).TryAcquireToken(); !ok {
+		t.Error("Expected token to be released, got none")
+	} else {
+		l.ReleaseToken(token)
+	}
+}
+
+func TestPanicPolicy_ConvertPanic(t *testing.T) {
+	l := NewTokenChanLimiter(1, WithPanicPolicy(ConvertPanic))
+	cause := errors.New("cause")
+
+	err := l.Invoke(func() error { panic(cause) })
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected *PanicError, got '%v'", err)
+	}
+	if pe.Value != cause {
+		t.Errorf("Expected panic value '%v', got '%v'", cause, pe.Value)
+	}
+	if len(pe.Stack) == 0 {
+		t.Error("Expected stack trace, got none")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected error to wrap panic value")
+	}
+	if _, ok := l.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released, got none")
+	}
+}
+
+func TestPanicPolicy_ConvertPanicReportsFailure(t *testing.T) {
+	l := NewFailBackOffLimiter(backoff.None, WithPanicPolicy(ConvertPanic))
+
+	if err := l.Invoke(func() error { panic("boom") }); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if l.failCount != 1 {
+		t.Errorf("Expected fail count '%d', got '%d'", 1, l.failCount)
+	}
+	if err := l.Invoke(func() error { return nil }); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	if l.failCount != 0 {
+		t.Errorf("Expected fail count '%d', got '%d'", 0, l.failCount)
+	}
+}
+
+func TestPanicPolicy_Goexit(t *testing.T) {
+	l := NewTokenChanLimiter(1, WithPanicPolicy(ConvertPanic))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Invoke(func() error {
+			runtime.Goexit()
+			return nil
+		})
+	}()
+	<-done
+
+	if _, ok := l.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released, got none")
+	}
+}
@@ -0,0 +1,145 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Pipe returns a channel which receives the values of the input channel, each after the RateLimiter permits it, for throttling a stage between producer and consumer goroutines.
+
+The returned channel is closed when the input channel is closed or the context is done, including while waiting on the limiter as CheckWaitContext does. When the context is done, the remaining input is drained and discarded in the background so that the producer is not blocked.
+*/
+func Pipe[T any](ctx context.Context, in <-chan T, l RateLimiter) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				go drain(in)
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if CheckWaitContext(ctx, l) != nil {
+					go drain(in)
+					return
+				}
+				select {
+				case <-ctx.Done():
+					go drain(in)
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+/*
+ParallelMap applies the passed function to the values of the input channel concurrently, holding a token from the TokenLimiter for each invocation, and returns a channel which receives the results. If the limiter satisfies the TokenAndFailLimiter interface, such as a TokenFailLimiter, each invocation's success/fail status is reported when its token is released.
+
+WithOrdered sets whether results are received in the order of their inputs. The default is the order in which invocations complete; ordered results hold their tokens until they are received, so no more results are buffered than there are tokens.
+
+Waiting for a token ends when the context is done, as AcquireTokenContext does. The first error returned by the function cancels the stage: no further invocations are started, the remaining input is drained and discarded, and the error is delivered on the error channel after the results channel is closed. If the context is done first, its error is delivered instead. The error channel is closed after at most one error. Consumers which stop receiving results before the results channel is closed must cancel the context.
+*/
+func ParallelMap[T, U any](ctx context.Context, in <-chan T, l TokenLimiter, f func(ctx context.Context, v T) (U, error), opts ...Option) (<-chan U, <-chan error) {
+	o := newOptions(opts)
+	out := make(chan U)
+	errc := make(chan error, 1)
+	stageCtx, cancel := context.WithCancel(ctx)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	release := func(token *[16]byte, success bool) {
+		if fl, ok := l.(TokenAndFailLimiter); ok {
+			fl.ReleaseTokenAndReport(token, success)
+			return
+		}
+		l.ReleaseToken(token)
+	}
+	worker := func(v T, token *[16]byte, prev <-chan struct{}, done chan<- struct{}) {
+		defer wg.Done()
+		if done != nil {
+			defer close(done)
+		}
+		u, err := f(stageCtx, v)
+		if prev != nil {
+			<-prev
+		}
+		if err != nil {
+			release(token, false)
+			fail(err)
+			return
+		}
+		if stageCtx.Err() == nil {
+			select {
+			case <-stageCtx.Done():
+			case out <- u:
+			}
+		}
+		release(token, true)
+	}
+
+	go func() {
+		defer func() {
+			wg.Wait()
+			cancel()
+			close(out)
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			if firstErr != nil {
+				errc <- firstErr
+			}
+			close(errc)
+		}()
+		var prev chan struct{}
+		if o.ordered {
+			prev = make(chan struct{})
+			close(prev)
+		}
+		for {
+			select {
+			case <-stageCtx.Done():
+				go drain(in)
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				token, err := AcquireTokenContext(stageCtx, l)
+				if err != nil {
+					go drain(in)
+					return
+				}
+				wg.Add(1)
+				if o.ordered {
+					done := make(chan struct{})
+					go worker(v, token, prev, done)
+					prev = done
+				} else {
+					go worker(v, token, nil, nil)
+				}
+			}
+		}
+	}()
+	return out, errc
+}
+
+// drain receives and discards the values of a channel until it is closed.
+func drain[T any](in <-chan T) {
+	for range in {
+	}
+}
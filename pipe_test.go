@@ -0,0 +1,245 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func sendAll[T any](values ...T) <-chan T {
+	in := make(chan T)
+	go func() {
+		defer close(in)
+		for _, v := range values {
+			in <- v
+		}
+	}()
+	return in
+}
+
+func TestPipe(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	l := NewFixedIntervalLimiter(time.Second, WithClock(c))
+	out := Pipe(context.Background(), sendAll(1, 2, 3), l)
+
+	if v := <-out; v != 1 {
+		t.Errorf("Expected '%d', got '%d'", 1, v)
+	}
+	for _, expected := range []int{2, 3} {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+		if v := <-out; v != expected {
+			t.Errorf("Expected '%d', got '%d'", expected, v)
+		}
+	}
+	if _, ok := <-out; ok {
+		t.Error("Expected output channel to be closed")
+	}
+}
+
+func TestPipe_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Pipe(ctx, in, NewBurstRateLimiter(NewRate(100, time.Second)))
+
+	in <- 1
+	cancel()
+	for range out {
+	}
+	// the remaining input is drained, so the producer is not blocked
+	select {
+	case in <- 2:
+	case <-time.After(time.Second):
+		t.Fatal("Expected input to be drained after cancel")
+	}
+	close(in)
+}
+
+func TestPipe_CancelWhileWaiting(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Pipe(ctx, in, NewFixedIntervalLimiter(time.Hour, WithClock(c)))
+
+	in <- 1
+	<-out
+	in <- 2
+	c.BlockUntil(1)
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("Expected output channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected output channel to be closed while waiting on the limiter")
+	}
+	close(in)
+}
+
+func TestParallelMap(t *testing.T) {
+	l := NewTokenChanLimiter(2)
+	var active, maxActive int32
+	out, errc := ParallelMap(context.Background(), sendAll(1, 2, 3, 4, 5), l, func(ctx context.Context, v int) (int, error) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return v * 10, nil
+	})
+
+	var results []int
+	for v := range out {
+		results = append(results, v)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	sort.Ints(results)
+	expected := []int{10, 20, 30, 40, 50}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected '%v', got '%v'", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("Expected '%v', got '%v'", expected, results)
+		}
+	}
+	if maxActive > 2 {
+		t.Errorf("Expected at most '%d' concurrent invocations, got '%d'", 2, maxActive)
+	}
+}
+
+func TestParallelMap_Ordered(t *testing.T) {
+	l := NewTokenChanLimiter(4)
+	out, errc := ParallelMap(context.Background(), sendAll(4, 3, 2, 1), l, func(ctx context.Context, v int) (int, error) {
+		// later inputs complete first
+		time.Sleep(time.Duration(v) * time.Millisecond)
+		return v, nil
+	}, WithOrdered(true))
+
+	var results []int
+	for v := range out {
+		results = append(results, v)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+	expected := []int{4, 3, 2, 1}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected '%v', got '%v'", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("Expected '%v', got '%v'", expected, results)
+		}
+	}
+}
+
+func TestParallelMap_Error(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	tl := NewTokenChanLimiter(1)
+	l := NewTokenFailLimiter(tl, fl)
+	expected := errors.New("error")
+
+	in := make(chan int)
+	go func() {
+		for i := 1; i <= 10; i += 1 {
+			in <- i
+		}
+		close(in)
+	}()
+	var calls int32
+	out, errc := ParallelMap(context.Background(), in, l, func(ctx context.Context, v int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		if v == 2 {
+			return 0, expected
+		}
+		return v, nil
+	})
+
+	for range out {
+	}
+	if err := <-errc; err != expected {
+		t.Errorf("Expected '%v', got '%v'", expected, err)
+	}
+	if _, ok := <-errc; ok {
+		t.Error("Expected error channel to be closed")
+	}
+	if n := atomic.LoadInt32(&calls); n > 3 {
+		t.Errorf("Expected invocations to stop after the error, got '%d'", n)
+	}
+	if fl.failCount != 1 {
+		t.Errorf("Expected fail count '%d', got '%d'", 1, fl.failCount)
+	}
+	// an acquisition abandoned by the stage releases its token in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := tl.TryAcquireToken(); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected token to be released, got none")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParallelMap_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out, errc := ParallelMap(ctx, in, NewTokenChanLimiter(1), func(ctx context.Context, v int) (int, error) {
+		return v, nil
+	})
+
+	in <- 1
+	cancel()
+	for range out {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+	select {
+	case in <- 2:
+	case <-time.After(time.Second):
+		t.Fatal("Expected input to be drained after cancel")
+	}
+	close(in)
+}
+
+func TestParallelMap_CancelWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	unblock := make(chan struct{})
+	out, errc := ParallelMap(ctx, in, NewTokenChanLimiter(1), func(ctx context.Context, v int) (int, error) {
+		<-unblock
+		return v, nil
+	})
+
+	// the first invocation holds the only token while the second input waits for it
+	in <- 1
+	in <- 2
+	cancel()
+	select {
+	case in <- 3:
+	case <-time.After(time.Second):
+		t.Fatal("Expected input to be drained while waiting for a token")
+	}
+	close(unblock)
+	for range out {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+	close(in)
+}
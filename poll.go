@@ -0,0 +1,70 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// poller calls a function at an interval in its own goroutine, for types which adjust, reload or save periodically between calls to their Start and Stop methods.
+type poller struct {
+	mu      sync.Mutex
+	onError func(error)
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// setErrorHandler sets the function which is called with errors returned by the polled function.
+func (p *poller) setErrorHandler(f func(error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onError = f
+}
+
+// start begins calling f at the interval, unless polling has already started. ErrInvalidInterval is returned if the interval is not positive.
+func (p *poller) start(clock Clock, interval time.Duration, f func() error) (err error) {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
+	go p.run(clock, interval, f, p.stop, p.done)
+	return
+}
+
+// halt ends polling and waits for any call in progress to complete.
+func (p *poller) halt() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (p *poller) run(clock Clock, interval time.Duration, f func() error, stop, done chan struct{}) {
+	defer close(done)
+	for {
+		timer := clock.NewTimer(interval)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if err := f(); err != nil {
+			p.mu.Lock()
+			onError := p.onError
+			p.mu.Unlock()
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
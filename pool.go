@@ -0,0 +1,165 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/*
+ErrPoolClosed is returned when a function is submitted to a Pool which has been shut down.
+*/
+var ErrPoolClosed = errors.New("Pool has been shut down.")
+
+/*
+Pool runs submitted functions in their own goroutines, holding a token from a TokenLimiter for the duration of each function and invoking it through any additional InvocationLimiters, and collects their errors.
+
+A function's token is acquired when it is submitted, so there are never more goroutines than tokens in use, and submitters are blocked while the Pool is at capacity. Because tokens are acquired for each function, changes to the supply of an AdjustableTokenChanLimiter take effect on a running Pool.
+*/
+type Pool struct {
+	mu          sync.Mutex
+	wg          sync.WaitGroup
+	ctx         context.Context
+	cancel      context.CancelFunc
+	tokens      TokenLimiter
+	limiters    []InvocationLimiter
+	failFast    bool
+	panicPolicy PanicPolicy
+	closed      bool
+	errs        []error
+}
+
+/*
+NewPool instantiates a new Pool which runs functions under the provided TokenLimiter and InvocationLimiters, in that order. The functions receive a context derived from the provided context, which is canceled when the Pool fails fast.
+*/
+func NewPool(ctx context.Context, tl TokenLimiter, limiters ...InvocationLimiter) (p *Pool) {
+	p = &Pool{
+		tokens:   tl,
+		limiters: limiters,
+	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return
+}
+
+/*
+SetFailFast sets whether the first error returned by a function cancels the Pool's context. Functions which have not started when the context is canceled are skipped.
+*/
+func (p *Pool) SetFailFast(failFast bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failFast = failFast
+}
+
+/*
+SetPanicPolicy sets what happens when a function panics. Under RePanic, the default, the panic continues in the function's goroutine after its token has been released. Under ConvertPanic, the panic is recorded as a *PanicError like any other error.
+*/
+func (p *Pool) SetPanicPolicy(policy PanicPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.panicPolicy = policy
+}
+
+/*
+Context returns the context passed to the Pool's functions.
+*/
+func (p *Pool) Context() context.Context {
+	return p.ctx
+}
+
+/*
+Go blocks until a token is acquired, then runs the function in its own goroutine. If the Pool's context is done first, the function is skipped.
+
+ErrPoolClosed is returned if the Pool has been shut down. Go must not be called concurrently with Wait.
+*/
+func (p *Pool) Go(f func(ctx context.Context) error) (err error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		err = ErrPoolClosed
+		return
+	}
+	p.wg.Add(1)
+	p.mu.Unlock()
+	token, ctxErr := AcquireTokenContext(p.ctx, p.tokens)
+	if ctxErr != nil {
+		p.wg.Done()
+		return
+	}
+	go p.run(f, token)
+	return
+}
+
+/*
+Wait blocks until all submitted functions have returned or been skipped, and returns the errors of the functions submitted since the previous call to Wait.
+
+When the Pool fails fast, the first error is returned. Otherwise all errors are returned, joined with errors.Join.
+*/
+func (p *Pool) Wait() (err error) {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	errs := p.errs
+	p.errs = nil
+	if p.failFast && len(errs) > 0 {
+		err = errs[0]
+		return
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+/*
+Shutdown stops the Pool from accepting new functions and blocks until all submitted functions have returned or the provided context is done, in which case the context's error is returned. Functions which are still running are not canceled.
+*/
+func (p *Pool) Shutdown(ctx context.Context) (err error) {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (p *Pool) run(f func(ctx context.Context) error, token *[16]byte) {
+	defer p.wg.Done()
+	if p.ctx.Err() != nil {
+		p.tokens.ReleaseToken(token)
+		return
+	}
+	p.mu.Lock()
+	policy := p.panicPolicy
+	p.mu.Unlock()
+	call := func() error {
+		return f(p.ctx)
+	}
+	for i := len(p.limiters) - 1; i >= 0; i -= 1 {
+		l, inner := p.limiters[i], call
+		call = func() error {
+			return l.Invoke(inner)
+		}
+	}
+	err := policy.invoke(call, func(bool) {
+		p.tokens.ReleaseToken(token)
+	})
+	if err != nil {
+		p.record(err)
+	}
+}
+
+func (p *Pool) record(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs = append(p.errs, err)
+	if p.failFast {
+		p.cancel()
+	}
+}
@@ -0,0 +1,175 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestPool(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	tl := NewAdjustableTokenChanLimiter(2, 4)
+	fl := NewFailBackOffLimiter(backoff.None)
+	p := NewPool(context.Background(), tl, fl)
+
+	var running, maxRunning int32
+	work := func(ctx context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		c.Sleep(time.Second)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+	// Go blocks while the Pool is at capacity, so functions are submitted from another goroutine
+	submit := func(n int) {
+		go func() {
+			for i := 0; i < n; i += 1 {
+				p.Go(work)
+			}
+		}()
+	}
+
+	submit(10)
+	for i := 0; i < 5; i += 1 {
+		c.BlockUntil(2)
+		if n := atomic.LoadInt32(&running); n != 2 {
+			t.Errorf("Expected 2, got %d", n)
+		}
+		c.Advance(time.Second)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if maxRunning != 2 {
+		t.Errorf("Expected 2, got %d", maxRunning)
+	}
+
+	tl.AddTokens(2)
+	atomic.StoreInt32(&maxRunning, 0)
+	submit(8)
+	for i := 0; i < 2; i += 1 {
+		c.BlockUntil(4)
+		c.Advance(time.Second)
+	}
+	p.Wait()
+	if maxRunning != 4 {
+		t.Errorf("Expected 4, got %d", maxRunning)
+	}
+}
+
+func TestPool_GoBlocksAtCapacity(t *testing.T) {
+	tl := NewTokenChanLimiter(1)
+	p := NewPool(context.Background(), tl)
+	release := make(chan struct{})
+	p.Go(func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	submitted := make(chan struct{})
+	go func() {
+		p.Go(func(context.Context) error { return nil })
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("Expected Go to block until a token is released")
+	default:
+	}
+	close(release)
+	<-submitted
+	if err := p.Wait(); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestPool_Errors(t *testing.T) {
+	p := NewPool(context.Background(), NewTokenChanLimiter(4))
+	errA, errB := errors.New("a"), errors.New("b")
+
+	p.Go(func(context.Context) error { return errA })
+	p.Go(func(context.Context) error { return nil })
+	p.Go(func(context.Context) error { return errB })
+
+	err := p.Wait()
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Expected both errors, got: %v", err)
+	}
+
+	// errors are not carried over to the next Wait
+	p.Go(func(context.Context) error { return nil })
+	if err := p.Wait(); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestPool_ConvertPanic(t *testing.T) {
+	tl := NewTokenChanLimiter(1)
+	p := NewPool(context.Background(), tl)
+	p.SetPanicPolicy(ConvertPanic)
+
+	p.Go(func(context.Context) error { panic("boom") })
+	var pe *PanicError
+	if err := p.Wait(); !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("Expected panic error, got: %v", err)
+	}
+	if _, ok := tl.TryAcquireToken(); !ok {
+		t.Error("Expected token to be released")
+	}
+}
+
+func TestPool_FailFast(t *testing.T) {
+	tl := NewTokenChanLimiter(1)
+	p := NewPool(context.Background(), tl)
+	p.SetFailFast(true)
+	errA := errors.New("a")
+
+	var ran int32
+	for i := 0; i < 10; i += 1 {
+		p.Go(func(context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return errA
+		})
+	}
+	if err := p.Wait(); err != errA {
+		t.Errorf("Expected '%v', got '%v'", errA, err)
+	}
+	if ran != 1 {
+		t.Errorf("Expected 1, got %d", ran)
+	}
+	if p.Context().Err() == nil {
+		t.Error("Expected context to be canceled")
+	}
+}
+
+func TestPool_Shutdown(t *testing.T) {
+	p := NewPool(context.Background(), NewTokenChanLimiter(1))
+	release := make(chan struct{})
+	p.Go(func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+	if err := p.Go(func(context.Context) error { return nil }); err != ErrPoolClosed {
+		t.Errorf("Expected '%v', got '%v'", ErrPoolClosed, err)
+	}
+
+	close(release)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
@@ -0,0 +1,406 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("Quota has been exhausted for the current period.")
+	ErrInvalidQuota  = errors.New("Quota limit must be greater than zero.")
+	ErrInvalidPeriod = errors.New("Quota period is not recognized.")
+	ErrNilLocation   = errors.New("Location must not be nil.")
+)
+
+/*
+QuotaPeriod is a calendar-aligned period after which a QuotaLimiter's usage resets.
+*/
+type QuotaPeriod int
+
+const (
+	// Hourly periods begin at the top of each hour.
+	Hourly QuotaPeriod = iota + 1
+	// Daily periods begin at midnight.
+	Daily
+	// Weekly periods begin at midnight on Monday.
+	Weekly
+	// Monthly periods begin at midnight on the first day of each month.
+	Monthly
+)
+
+/*
+Start returns the beginning of the period containing the provided time, in the time's location.
+*/
+func (p QuotaPeriod) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch p {
+	case Hourly:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+	case Daily:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case Weekly:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
+
+/*
+Next returns the beginning of the period following the one containing the provided time, in the time's location.
+*/
+func (p QuotaPeriod) Next(t time.Time) time.Time {
+	start := p.Start(t)
+	switch p {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return t
+}
+
+func (p QuotaPeriod) validate() error {
+	if p < Hourly || p > Monthly {
+		return ErrInvalidPeriod
+	}
+	return nil
+}
+
+/*
+QuotaState is the persisted usage of a QuotaLimiter: the start of the current period and the quantity used within it.
+*/
+type QuotaState struct {
+	PeriodStart time.Time `json:"period_start"`
+	Used        int       `json:"used"`
+}
+
+/*
+QuotaStore is the interface that wraps the LoadQuota and SaveQuota methods, which persist a QuotaLimiter's usage so that it survives restarts.
+
+LoadQuota returns the last saved state, or the zero state if none has been saved. SaveQuota is called with the latest state after quota is consumed, from a background goroutine; calls are never concurrent, and consumption which occurs during a call is saved by the next one.
+*/
+type QuotaStore interface {
+	LoadQuota() (QuotaState, error)
+	SaveQuota(s QuotaState) error
+}
+
+/*
+FileQuotaStore is a QuotaStore which saves state as JSON in a single file. Each save replaces the file atomically and syncs it to disk, so a crash never leaves a partially written state. Saves happen in the background, so consumption does not wait for the disk, but usage since the last completed save is lost on a crash.
+*/
+type FileQuotaStore struct {
+	path string
+}
+
+/*
+NewFileQuotaStore instantiates a new FileQuotaStore which saves state in the file at the provided path. The file's directory must exist.
+*/
+func NewFileQuotaStore(path string) (s *FileQuotaStore) {
+	s = &FileQuotaStore{
+		path: path,
+	}
+	return
+}
+
+/*
+LoadQuota reads the state from the file, returning the zero state if the file does not exist.
+*/
+func (s *FileQuotaStore) LoadQuota() (state QuotaState, err error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &state)
+	return
+}
+
+/*
+SaveQuota writes the state to a temporary file in the same directory and renames it over the file.
+*/
+func (s *FileQuotaStore) SaveQuota(state QuotaState) (err error) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to the path, so readers see either the old or the new contents.
+func writeFileAtomic(path string, b []byte) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
+
+/*
+QuotaUsage is a snapshot of a QuotaLimiter's usage within its current period.
+*/
+type QuotaUsage struct {
+	Limit       int
+	Used        int
+	Remaining   int
+	PeriodStart time.Time
+	ResetAt     time.Time
+}
+
+/*
+QuotaLimiter enforces a hard limit on the quantity consumed within a calendar-aligned period, such as 10,000 calls per day resetting at midnight UTC, and satisfies the InvocationLimiter interface.
+
+Unlike rate limiters, a QuotaLimiter never waits: once the quota is exhausted, actions are refused until the next period begins. An optional soft limit calls a function once per period when usage reaches it, so callers can warn before the hard limit is hit. An optional QuotaStore persists usage across restarts; usage is saved in the background, and Flush should be called before exit to save the latest usage.
+*/
+type QuotaLimiter struct {
+	mu          sync.Mutex
+	clock       Clock
+	location    *time.Location
+	period      QuotaPeriod
+	limit       int
+	softLimit   int
+	onSoft      func(QuotaUsage)
+	store       QuotaStore
+	state       QuotaState
+	dirty       bool
+	saving      bool
+	saved       chan struct{}
+	saveErr     error
+	panicPolicy PanicPolicy
+}
+
+/*
+NewQuotaLimiter instantiates a new QuotaLimiter which permits the provided quantity per period.
+
+It panics with an *OptionError if the limit or period is invalid or the QuotaStore fails to load; BuildQuotaLimiter returns the error instead.
+*/
+func NewQuotaLimiter(limit int, period QuotaPeriod, opts ...Option) (l *QuotaLimiter) {
+	l, err := BuildQuotaLimiter(append([]Option{WithQuota(limit, period)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildQuotaLimiter instantiates a new QuotaLimiter configured by the provided options, or returns an error if they are invalid.
+
+WithQuota is required. WithLocation sets the time zone of period boundaries, which defaults to UTC. WithSoftLimit and WithQuotaStore are optional; if a store is provided, its state is loaded and an error from loading it is returned.
+*/
+func BuildQuotaLimiter(opts ...Option) (l *QuotaLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithQuota", "WithLocation", "WithSoftLimit", "WithQuotaStore"), o.validateClock(), o.validateQuota()); err != nil {
+		return
+	}
+	l = &QuotaLimiter{
+		clock:       o.clock,
+		location:    o.location,
+		period:      o.period,
+		limit:       o.quota,
+		softLimit:   o.softLimit,
+		onSoft:      o.onSoftLimit,
+		store:       o.quotaStore,
+		panicPolicy: o.panicPolicy,
+	}
+	if l.location == nil {
+		l.location = time.UTC
+	}
+	if l.store != nil {
+		if l.state, err = l.store.LoadQuota(); err != nil {
+			l = nil
+			return
+		}
+	}
+	return
+}
+
+/*
+Take consumes the provided quantity from the current period's quota. It does not wait for the QuotaStore to save the new usage; errors from saving are returned by Flush.
+
+ErrQuotaExceeded is returned, and nothing is consumed, if less than the quantity remains.
+*/
+func (l *QuotaLimiter) Take(n int) (err error) {
+	if n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := l.clock.Now().In(l.location)
+	l.rollover(now)
+	if l.state.Used+n > l.limit {
+		l.mu.Unlock()
+		return ErrQuotaExceeded
+	}
+	prev := l.state
+	l.state.Used += n
+	if l.store != nil {
+		l.dirty = true
+		if !l.saving {
+			l.saving = true
+			l.saved = make(chan struct{})
+			go l.saveInBackground(l.saved)
+		}
+	}
+	crossedSoft := l.onSoft != nil && l.softLimit > 0 && prev.Used < l.softLimit && l.state.Used >= l.softLimit
+	usage := l.usage(now)
+	l.mu.Unlock()
+	if crossedSoft {
+		l.onSoft(usage)
+	}
+	return
+}
+
+/*
+Allow consumes one unit of quota and returns true if the quota permits an action, otherwise it returns false without blocking.
+*/
+func (l *QuotaLimiter) Allow() bool {
+	return l.Take(1) == nil
+}
+
+/*
+Refund returns one unit of quota to the current period, for an action which was allowed but then abandoned before it was taken. The refund is persisted with the next consumption.
+*/
+func (l *QuotaLimiter) Refund() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollover(l.clock.Now().In(l.location))
+	if l.state.Used > 0 {
+		l.state.Used -= 1
+	}
+}
+
+/*
+Invoke consumes one unit of quota and invokes the passed function. If the quota is exhausted, ErrQuotaExceeded is returned and the function is not invoked. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *QuotaLimiter) Invoke(f func() error) (err error) {
+	if err = l.Take(1); err != nil {
+		return
+	}
+	return l.panicPolicy.invoke(f, nil)
+}
+
+/*
+Flush waits for any save in progress and saves usage which has not yet been saved, so the QuotaStore holds the latest usage when it returns. It returns the error of the first save which has failed since the previous Flush, if any; usage which failed to save is saved again by the next save.
+*/
+func (l *QuotaLimiter) Flush() (err error) {
+	if l.store == nil {
+		return
+	}
+	l.mu.Lock()
+	for l.saving {
+		saved := l.saved
+		l.mu.Unlock()
+		<-saved
+		l.mu.Lock()
+	}
+	if l.dirty {
+		l.saving = true
+		saved := make(chan struct{})
+		l.saved = saved
+		l.mu.Unlock()
+		l.save()
+		close(saved)
+		l.mu.Lock()
+	}
+	err, l.saveErr = l.saveErr, nil
+	l.mu.Unlock()
+	return
+}
+
+/*
+Remaining returns the quantity which may still be consumed in the current period.
+*/
+func (l *QuotaLimiter) Remaining() int {
+	return l.Usage().Remaining
+}
+
+/*
+ResetAt returns the time at which the current period ends and usage resets.
+*/
+func (l *QuotaLimiter) ResetAt() time.Time {
+	return l.Usage().ResetAt
+}
+
+/*
+Usage returns a snapshot of the limiter's usage within the current period.
+*/
+func (l *QuotaLimiter) Usage() QuotaUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now().In(l.location)
+	l.rollover(now)
+	return l.usage(now)
+}
+
+func (l *QuotaLimiter) saveInBackground(saved chan struct{}) {
+	defer close(saved)
+	l.save()
+}
+
+// save saves the latest state until no unsaved usage remains or a save fails, recording the first error for Flush, then clears saving. The caller must have set saving.
+func (l *QuotaLimiter) save() {
+	var err error
+	for {
+		l.mu.Lock()
+		if err != nil {
+			l.dirty = true
+			if l.saveErr == nil {
+				l.saveErr = err
+			}
+		}
+		if !l.dirty || err != nil {
+			l.saving = false
+			l.mu.Unlock()
+			return
+		}
+		l.dirty = false
+		state := l.state
+		l.mu.Unlock()
+		err = l.store.SaveQuota(state)
+	}
+}
+
+// rollover resets usage if the current period has ended; the reset is persisted with the next consumption.
+func (l *QuotaLimiter) rollover(now time.Time) {
+	if start := l.period.Start(now); !start.Equal(l.state.PeriodStart) {
+		l.state = QuotaState{PeriodStart: start}
+	}
+}
+
+func (l *QuotaLimiter) usage(now time.Time) (u QuotaUsage) {
+	u = QuotaUsage{
+		Limit:       l.limit,
+		Used:        l.state.Used,
+		Remaining:   l.limit - l.state.Used,
+		PeriodStart: l.state.PeriodStart,
+		ResetAt:     l.period.Next(now),
+	}
+	if u.Remaining < 0 {
+		u.Remaining = 0
+	}
+	return
+}
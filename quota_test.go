@@ -0,0 +1,185 @@
+package limiter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriod(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	now := time.Date(2019, 3, 10, 15, 30, 0, 0, ny)
+	tests := []struct {
+		period      QuotaPeriod
+		start, next time.Time
+	}{
+		{Hourly, time.Date(2019, 3, 10, 15, 0, 0, 0, ny), time.Date(2019, 3, 10, 16, 0, 0, 0, ny)},
+		{Daily, time.Date(2019, 3, 10, 0, 0, 0, 0, ny), time.Date(2019, 3, 11, 0, 0, 0, 0, ny)},
+		{Weekly, time.Date(2019, 3, 4, 0, 0, 0, 0, ny), time.Date(2019, 3, 11, 0, 0, 0, 0, ny)},
+		{Monthly, time.Date(2019, 3, 1, 0, 0, 0, 0, ny), time.Date(2019, 4, 1, 0, 0, 0, 0, ny)},
+	}
+	for _, test := range tests {
+		if start := test.period.Start(now); !start.Equal(test.start) {
+			t.Errorf("%d: expected start %s, got %s", test.period, test.start, start)
+		}
+		if next := test.period.Next(now); !next.Equal(test.next) {
+			t.Errorf("%d: expected next %s, got %s", test.period, test.next, next)
+		}
+	}
+	// the day of a daylight saving change is 23 hours long
+	if d := Daily.Next(now).Sub(Daily.Start(now)); d != 23*time.Hour {
+		t.Errorf("Expected 23h, got %s", d)
+	}
+}
+
+func TestQuotaLimiter(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 23, 0, 0, 0, time.UTC))
+	var warned []QuotaUsage
+	l := NewQuotaLimiter(3, Daily, WithClock(c), WithSoftLimit(2, func(u QuotaUsage) {
+		warned = append(warned, u)
+	}))
+
+	if !l.Allow() {
+		t.Fatal("Expected first action to be allowed")
+	}
+	if err := l.Invoke(func() error { return errors.New("error") }); err == nil || errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected error from function, got '%v'", err)
+	}
+	if len(warned) != 1 || warned[0].Used != 2 || warned[0].Remaining != 1 {
+		t.Fatalf("Expected 1 warning at 2 used, got %v", warned)
+	}
+	if err := l.Take(2); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected '%v', got '%v'", ErrQuotaExceeded, err)
+	}
+	if l.Remaining() != 1 {
+		t.Fatalf("Expected 1 remaining, got %d", l.Remaining())
+	}
+	l.Take(1)
+	invoked := false
+	if err := l.Invoke(func() error { invoked = true; return nil }); !errors.Is(err, ErrQuotaExceeded) || invoked {
+		t.Fatalf("Expected '%v' without invocation, got '%v'", ErrQuotaExceeded, err)
+	}
+	if expected := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC); !l.ResetAt().Equal(expected) {
+		t.Errorf("Expected reset at %s, got %s", expected, l.ResetAt())
+	}
+	if len(warned) != 1 {
+		t.Errorf("Expected 1 warning, got %d", len(warned))
+	}
+
+	c.Advance(time.Hour)
+	if l.Remaining() != 3 {
+		t.Fatalf("Expected 3 remaining after reset, got %d", l.Remaining())
+	}
+	l.Take(2)
+	if len(warned) != 2 {
+		t.Errorf("Expected warning in new period, got %d", len(warned))
+	}
+}
+
+func TestQuotaLimiter_Store(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 31, 12, 0, 0, 0, time.UTC))
+	store := NewFileQuotaStore(filepath.Join(t.TempDir(), "quota.json"))
+
+	l := NewQuotaLimiter(10, Monthly, WithClock(c), WithQuotaStore(store))
+	if err := l.Take(7); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+
+	l = NewQuotaLimiter(10, Monthly, WithClock(c), WithQuotaStore(store))
+	if l.Remaining() != 3 {
+		t.Fatalf("Expected 3 remaining after restart, got %d", l.Remaining())
+	}
+
+	c.Advance(24 * time.Hour)
+	l = NewQuotaLimiter(10, Monthly, WithClock(c), WithQuotaStore(store))
+	if l.Remaining() != 10 {
+		t.Fatalf("Expected 10 remaining in new month, got %d", l.Remaining())
+	}
+
+	failing := &failingQuotaStore{}
+	l = NewQuotaLimiter(10, Monthly, WithClock(c), WithQuotaStore(failing))
+	if err := l.Take(1); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err := l.Flush(); err != errFailingStore {
+		t.Fatalf("Expected store error, got '%v'", err)
+	}
+	if l.Remaining() != 9 {
+		t.Errorf("Expected 9 remaining, got %d", l.Remaining())
+	}
+}
+
+func TestQuotaLimiter_SaveDoesNotBlock(t *testing.T) {
+	store := &blockingQuotaStore{release: make(chan struct{}), saved: make(chan QuotaState, 10)}
+	l := NewQuotaLimiter(10, Daily, WithQuotaStore(store))
+
+	// the first save blocks, and consumption continues without waiting for it
+	for i := 0; i < 3; i += 1 {
+		if err := l.Take(1); err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+	}
+	close(store.release)
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	var last QuotaState
+	for len(store.saved) > 0 {
+		last = <-store.saved
+	}
+	if last.Used != 3 {
+		t.Errorf("Expected last saved usage '%d', got '%d'", 3, last.Used)
+	}
+}
+
+type blockingQuotaStore struct {
+	release chan struct{}
+	saved   chan QuotaState
+}
+
+func (*blockingQuotaStore) LoadQuota() (QuotaState, error) {
+	return QuotaState{}, nil
+}
+
+func (s *blockingQuotaStore) SaveQuota(state QuotaState) error {
+	<-s.release
+	s.saved <- state
+	return nil
+}
+
+func TestBuildQuotaLimiter_Errors(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		err  error
+	}{
+		{nil, ErrMissingOption},
+		{[]Option{WithQuota(0, Daily)}, ErrInvalidQuota},
+		{[]Option{WithQuota(1, QuotaPeriod(0))}, ErrInvalidPeriod},
+		{[]Option{WithQuota(1, Daily), WithLocation(nil)}, ErrNilLocation},
+	}
+	for _, test := range tests {
+		var oe *OptionError
+		if _, err := BuildQuotaLimiter(test.opts...); !errors.As(err, &oe) || !errors.Is(err, test.err) {
+			t.Errorf("Expected '%v', got '%v'", test.err, err)
+		}
+	}
+}
+
+var errFailingStore = errors.New("error")
+
+type failingQuotaStore struct{}
+
+func (*failingQuotaStore) LoadQuota() (QuotaState, error) {
+	return QuotaState{}, nil
+}
+
+func (*failingQuotaStore) SaveQuota(QuotaState) error {
+	return errFailingStore
+}
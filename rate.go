@@ -1,9 +1,17 @@
 package limiter
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
 	"time"
 )
 
+/*
+Rate is a number of actions permitted per duration. Rates can be parsed from and formatted as strings like "500/s", and used directly as flag values and in JSON or text-encoded configuration.
+*/
 type Rate struct {
 	Count    int
 	Duration time.Duration
@@ -16,3 +24,194 @@ func NewRate(count int, duration time.Duration) (r Rate) {
 	}
 	return
 }
+
+/*
+Validate returns ErrInvalidRate if the rate's count or duration is not positive.
+*/
+func (r Rate) Validate() (err error) {
+	if r.Count <= 0 || r.Duration <= 0 {
+		err = ErrInvalidRate
+	}
+	return
+}
+
+/*
+Per returns the number of actions this rate permits over the provided duration, which may be fractional.
+*/
+func (r Rate) Per(d time.Duration) float64 {
+	return float64(r.Count) * float64(d) / float64(r.Duration)
+}
+
+/*
+Interval returns the average time between actions at this rate, or 0 if the count is not positive.
+*/
+func (r Rate) Interval() time.Duration {
+	if r.Count <= 0 {
+		return 0
+	}
+	return r.Duration / time.Duration(r.Count)
+}
+
+/*
+Normalize returns the equivalent rate with the count and duration reduced to lowest terms, so that equivalent rates like "60/m" and "1/s" are equal.
+*/
+func (r Rate) Normalize() Rate {
+	g := gcd(int64(r.Count), int64(r.Duration))
+	if g <= 1 {
+		return r
+	}
+	return NewRate(r.Count/int(g), r.Duration/time.Duration(g))
+}
+
+/*
+Compare returns -1 if this rate permits fewer actions per unit of time than the other, 1 if it permits more, and 0 if the rates are equivalent. The comparison is exact and does not overflow.
+*/
+func (r Rate) Compare(other Rate) int {
+	a := new(big.Int).Mul(big.NewInt(int64(r.Count)), big.NewInt(int64(other.Duration)))
+	b := new(big.Int).Mul(big.NewInt(int64(other.Count)), big.NewInt(int64(r.Duration)))
+	return a.Cmp(b)
+}
+
+/*
+String formats the rate in the form accepted by ParseRate, such as "500/s", "10/100ms" or "3/2m".
+*/
+func (r Rate) String() string {
+	return strconv.Itoa(r.Count) + "/" + formatRateDuration(r.Duration)
+}
+
+/*
+Set parses the provided string with ParseRate and assigns the result, satisfying the flag.Value interface.
+*/
+func (r *Rate) Set(s string) (err error) {
+	var parsed Rate
+	if parsed, err = ParseRate(s); err != nil {
+		return
+	}
+	*r = parsed
+	return
+}
+
+/*
+MarshalText formats the rate with String, satisfying the encoding.TextMarshaler interface. The zero Rate is formatted as an empty string.
+*/
+func (r Rate) MarshalText() ([]byte, error) {
+	if r == (Rate{}) {
+		return []byte{}, nil
+	}
+	return []byte(r.String()), nil
+}
+
+/*
+UnmarshalText parses the rate with ParseRate, satisfying the encoding.TextUnmarshaler interface. An empty string is decoded as the zero Rate.
+*/
+func (r *Rate) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*r = Rate{}
+		return nil
+	}
+	return r.Set(string(b))
+}
+
+/*
+MarshalJSON encodes the rate as a JSON string in the form produced by MarshalText, so the zero Rate is encoded as an empty string.
+*/
+func (r Rate) MarshalJSON() ([]byte, error) {
+	b, _ := r.MarshalText()
+	return json.Marshal(string(b))
+}
+
+/*
+UnmarshalJSON decodes a rate from a JSON string in any form accepted by ParseRate, or from an object with Count and Duration (in nanoseconds) fields. An empty string is decoded as the zero Rate, and null leaves the rate unchanged, as it does for other JSON values.
+*/
+func (r *Rate) UnmarshalJSON(b []byte) (err error) {
+	if string(b) == "null" {
+		return
+	}
+	var s string
+	if err = json.Unmarshal(b, &s); err == nil {
+		return r.UnmarshalText([]byte(s))
+	}
+	var fields struct {
+		Count    int
+		Duration time.Duration
+	}
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return
+	}
+	*r = NewRate(fields.Count, fields.Duration)
+	return
+}
+
+/*
+ParseRate parses a rate in the form "count/duration" or "count per duration", such as "100/1s", "10/100ms" or "1 per 2s". The duration's number may be omitted when it is 1, as in "500/s" or "3600/h", and the duration may be given in days, as in "1000/d".
+
+An error wrapping ErrInvalidRate is returned if the string cannot be parsed or describes an invalid rate.
+*/
+func ParseRate(s string) (r Rate, err error) {
+	sep := "/"
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		sep = " per "
+		i = strings.Index(strings.ToLower(s), sep)
+	}
+	if i < 0 {
+		err = fmt.Errorf("%w: %q is not in the form count/duration", ErrInvalidRate, s)
+		return
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(s[:i]))
+	if err != nil {
+		err = fmt.Errorf("%w: %q has an invalid count", ErrInvalidRate, s)
+		return
+	}
+	d, err := parseRateDuration(strings.TrimSpace(s[i+len(sep):]))
+	if err != nil {
+		err = fmt.Errorf("%w: %q has an invalid duration", ErrInvalidRate, s)
+		return
+	}
+	r = NewRate(count, d)
+	if err = r.Validate(); err != nil {
+		err = fmt.Errorf("%w: %q must have a positive count and duration", ErrInvalidRate, s)
+	}
+	return
+}
+
+// parseRateDuration parses a duration which may omit a leading 1, like "s", or be given in days, like "2d".
+func parseRateDuration(s string) (d time.Duration, err error) {
+	if s != "" && (s[0] < '0' || s[0] > '9') && s[0] != '.' {
+		s = "1" + s
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n float64
+		if n, err = strconv.ParseFloat(days, 64); err != nil {
+			return
+		}
+		d = time.Duration(n * float64(24*time.Hour))
+		return
+	}
+	return time.ParseDuration(s)
+}
+
+// formatRateDuration formats a duration without zero-valued trailing units, and without the number when it is a single unit, like "s" or "h".
+func formatRateDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	if unit, ok := strings.CutPrefix(s, "1"); ok && strings.Trim(unit, "hmsuµn") == "" {
+		s = unit
+	}
+	return s
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		a = -a
+	}
+	return a
+}
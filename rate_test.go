@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := map[string]Rate{
+		"100/1s":   NewRate(100, time.Second),
+		"500/s":    NewRate(500, time.Second),
+		"10/100ms": NewRate(10, 100*time.Millisecond),
+		"3600/h":   NewRate(3600, time.Hour),
+		"1000/d":   NewRate(1000, 24*time.Hour),
+		"1 per 2s": NewRate(1, 2*time.Second),
+		"5 PER m":  NewRate(5, time.Minute),
+		" 3 / 2m ": NewRate(3, 2*time.Minute),
+	}
+	for s, expected := range tests {
+		if r, err := ParseRate(s); err != nil || r != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", s, expected, r, err)
+		}
+	}
+	for _, s := range []string{"", "100", "x/1s", "100/x", "0/1s", "1/0s", "1 per", "per 1s"} {
+		if _, err := ParseRate(s); !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("%q: expected '%v', got '%v'", s, ErrInvalidRate, err)
+		}
+	}
+}
+
+func TestRate_String(t *testing.T) {
+	tests := map[Rate]string{
+		NewRate(500, time.Second):                 "500/s",
+		NewRate(10, 100*time.Millisecond):         "10/100ms",
+		NewRate(3600, time.Hour):                  "3600/h",
+		NewRate(3, 2*time.Minute):                 "3/2m",
+		NewRate(1, 90*time.Minute):                "1/1h30m",
+		NewRate(2, 1500*time.Millisecond):         "2/1.5s",
+		NewRate(1, time.Millisecond):              "1/ms",
+		NewRate(7, 10*time.Second):                "7/10s",
+		NewRate(1, 24*time.Hour):                  "1/24h",
+		NewRate(4, time.Hour+30*time.Millisecond): "4/1h0m0.03s",
+	}
+	for r, expected := range tests {
+		s := r.String()
+		if s != expected {
+			t.Errorf("Expected %q, got %q", expected, s)
+		}
+		if parsed, err := ParseRate(s); err != nil || parsed != r {
+			t.Errorf("%q: expected round trip to %v, got %v (%v)", s, r, parsed, err)
+		}
+	}
+}
+
+func TestRate_Encoding(t *testing.T) {
+	var v struct {
+		Rate Rate
+		Ptr  *Rate
+	}
+	if err := json.Unmarshal([]byte(`{"Rate": "10/100ms", "Ptr": {"Count": 2, "Duration": 1000000000}}`), &v); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if v.Rate != NewRate(10, 100*time.Millisecond) || *v.Ptr != NewRate(2, time.Second) {
+		t.Errorf("Unexpected rates %v and %v", v.Rate, *v.Ptr)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if expected := `{"Rate":"10/100ms","Ptr":"2/s"}`; string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+	if err := json.Unmarshal([]byte(`{"Rate": "fast"}`), &v); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidRate, err)
+	}
+
+	// the zero rate round-trips, and null is also accepted
+	var zero struct{ Rate Rate }
+	if b, err = json.Marshal(zero); err != nil || string(b) != `{"Rate":""}` {
+		t.Errorf("Expected %s, got %s (%v)", `{"Rate":""}`, b, err)
+	}
+	v.Rate = NewRate(1, time.Second)
+	if err := json.Unmarshal(b, &v); err != nil || v.Rate != (Rate{}) {
+		t.Errorf("Expected zero rate, got %v (%v)", v.Rate, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Rate": null}`), &zero); err != nil || zero.Rate != (Rate{}) {
+		t.Errorf("Expected zero rate, got %v (%v)", zero.Rate, err)
+	}
+
+	r := NewRate(1, time.Second)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&r, "rate", "request rate")
+	if err := fs.Parse([]string{"-rate", "3600/h"}); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if r != NewRate(3600, time.Hour) {
+		t.Errorf("Expected 3600/h, got %v", r)
+	}
+}
+
+func TestRate_Conversion(t *testing.T) {
+	r := NewRate(3600, time.Hour)
+	if per := r.Per(time.Second); per != 1 {
+		t.Errorf("Expected 1, got %f", per)
+	}
+	if per := r.Per(100 * time.Millisecond); per != 0.1 {
+		t.Errorf("Expected 0.1, got %f", per)
+	}
+	if i := NewRate(10, time.Second).Interval(); i != 100*time.Millisecond {
+		t.Errorf("Expected 100ms, got %s", i)
+	}
+	if n := r.Normalize(); n != NewRate(1, time.Second) {
+		t.Errorf("Expected 1/s, got %v", n)
+	}
+	if n := NewRate(7, 3*time.Nanosecond).Normalize(); n != NewRate(7, 3*time.Nanosecond) {
+		t.Errorf("Expected 7/3ns, got %v", n)
+	}
+
+	tests := []struct {
+		a, b     Rate
+		expected int
+	}{
+		{NewRate(60, time.Minute), NewRate(1, time.Second), 0},
+		{NewRate(59, time.Minute), NewRate(1, time.Second), -1},
+		{NewRate(1, time.Millisecond), NewRate(999, time.Second), 1},
+		{NewRate(1<<62, time.Duration(1<<62)), NewRate(1<<62-1, time.Duration(1<<62)), 1},
+	}
+	for _, test := range tests {
+		if c := test.a.Compare(test.b); c != test.expected {
+			t.Errorf("%v vs %v: expected %d, got %d", test.a, test.b, test.expected, c)
+		}
+	}
+}
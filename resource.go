@@ -0,0 +1,245 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrInvalidResources       = errors.New("Resources must name at least one resource, each with a capacity greater than zero.")
+	ErrInvalidResourceRequest = errors.New("Resource request must name known resources in quantities not exceeding their capacity.")
+	ErrResourcesReleased      = errors.New("Resource set has already been released.")
+)
+
+/*
+ResourceLimiter limits concurrency over several named resource pools, such as CPU slots and database connections, acquiring the quantities a caller needs from every pool at once.
+
+Acquisition is all-or-nothing, so callers holding partial sets cannot deadlock each other. Requests are granted in the order they arrive: a request which cannot yet be satisfied holds back later requests, so large requests are not starved by small ones.
+*/
+type ResourceLimiter struct {
+	mu          sync.Mutex
+	capacity    map[string]uint
+	available   map[string]uint
+	waiters     []*resourceWaiter
+	panicPolicy PanicPolicy
+}
+
+type resourceWaiter struct {
+	request map[string]uint
+	ready   chan struct{}
+}
+
+/*
+ResourceSet holds quantities acquired from a ResourceLimiter, and returns all of them to their pools with one call to Release.
+*/
+type ResourceSet struct {
+	mu         sync.Mutex
+	owner      *ResourceLimiter
+	quantities map[string]uint
+	released   bool
+}
+
+/*
+NewResourceLimiter instantiates a new ResourceLimiter with pools of the provided capacities, keyed by resource name.
+
+It panics with an *OptionError if there are no resources or any capacity is 0; BuildResourceLimiter returns the error instead.
+*/
+func NewResourceLimiter(capacity map[string]uint, opts ...Option) (l *ResourceLimiter) {
+	l, err := BuildResourceLimiter(append([]Option{WithResources(capacity)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildResourceLimiter instantiates a new ResourceLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithResources is required.
+*/
+func BuildResourceLimiter(opts ...Option) (l *ResourceLimiter, err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithResources"); err != nil {
+		return
+	}
+	if len(o.resources) == 0 {
+		err = &OptionError{"WithResources", ErrInvalidResources}
+		return
+	}
+	l = &ResourceLimiter{
+		capacity:    make(map[string]uint, len(o.resources)),
+		available:   make(map[string]uint, len(o.resources)),
+		panicPolicy: o.panicPolicy,
+	}
+	for name, n := range o.resources {
+		if n == 0 {
+			return nil, &OptionError{"WithResources", ErrInvalidResources}
+		}
+		l.capacity[name] = n
+		l.available[name] = n
+	}
+	return
+}
+
+/*
+Acquire blocks until all of the requested quantities can be acquired together, or the context is done, in which case nothing is acquired and the context's error is returned.
+
+ErrInvalidResourceRequest is returned if the request names an unknown resource or exceeds a pool's capacity, since it could never be satisfied.
+*/
+func (l *ResourceLimiter) Acquire(ctx context.Context, request map[string]uint) (s *ResourceSet, err error) {
+	if err = l.validate(request); err != nil {
+		return
+	}
+	request = copyQuantities(request)
+	l.mu.Lock()
+	if len(l.waiters) == 0 && l.satisfiable(request) {
+		s = l.take(request)
+		l.mu.Unlock()
+		return
+	}
+	w := &resourceWaiter{request: request, ready: make(chan struct{})}
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return &ResourceSet{owner: l, quantities: request}, nil
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// granted while the context was being cancelled
+		return &ResourceSet{owner: l, quantities: request}, nil
+	default:
+	}
+	for i, other := range l.waiters {
+		if other == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			break
+		}
+	}
+	// removing this waiter may allow the ones behind it to proceed
+	l.grant()
+	return nil, ctx.Err()
+}
+
+/*
+TryAcquire acquires all of the requested quantities if they are immediately available and no other request is waiting, otherwise it returns false without blocking.
+*/
+func (l *ResourceLimiter) TryAcquire(request map[string]uint) (s *ResourceSet, ok bool) {
+	if l.validate(request) != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.waiters) == 0 && l.satisfiable(request) {
+		s, ok = l.take(copyQuantities(request)), true
+	}
+	return
+}
+
+/*
+Invoke acquires the requested quantities for the invocation of the passed function, and releases them when it returns. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *ResourceLimiter) Invoke(ctx context.Context, request map[string]uint, f func() error) (err error) {
+	s, err := l.Acquire(ctx, request)
+	if err != nil {
+		return
+	}
+	return l.panicPolicy.invoke(f, func(bool) {
+		s.Release()
+	})
+}
+
+/*
+Available returns the quantity of the named resource which is not held.
+*/
+func (l *ResourceLimiter) Available(name string) uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.available[name]
+}
+
+/*
+Capacity returns the capacity of the named resource's pool.
+*/
+func (l *ResourceLimiter) Capacity(name string) uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.capacity[name]
+}
+
+/*
+Quantities returns a copy of the quantities held by the set.
+*/
+func (s *ResourceSet) Quantities() map[string]uint {
+	return copyQuantities(s.quantities)
+}
+
+/*
+Release returns all of the set's quantities to their pools. ErrResourcesReleased is returned if the set has already been released.
+*/
+func (s *ResourceSet) Release() (err error) {
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		return ErrResourcesReleased
+	}
+	s.released = true
+	s.mu.Unlock()
+	l := s.owner
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name, n := range s.quantities {
+		l.available[name] += n
+	}
+	l.grant()
+	return
+}
+
+func (l *ResourceLimiter) validate(request map[string]uint) error {
+	for name, n := range request {
+		if n > l.capacity[name] {
+			return ErrInvalidResourceRequest
+		}
+	}
+	return nil
+}
+
+// satisfiable, take and grant must be called with mu held.
+func (l *ResourceLimiter) satisfiable(request map[string]uint) bool {
+	for name, n := range request {
+		if l.available[name] < n {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *ResourceLimiter) take(request map[string]uint) *ResourceSet {
+	for name, n := range request {
+		l.available[name] -= n
+	}
+	return &ResourceSet{owner: l, quantities: request}
+}
+
+// grant takes resources for waiters in order, stopping at the first which cannot be satisfied.
+func (l *ResourceLimiter) grant() {
+	for len(l.waiters) > 0 && l.satisfiable(l.waiters[0].request) {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		for name, n := range w.request {
+			l.available[name] -= n
+		}
+		close(w.ready)
+	}
+}
+
+func copyQuantities(m map[string]uint) map[string]uint {
+	c := make(map[string]uint, len(m))
+	for name, n := range m {
+		c[name] = n
+	}
+	return c
+}
@@ -0,0 +1,152 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResourceLimiter(t *testing.T) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 4, "db": 1})
+
+	s, err := l.Acquire(context.Background(), map[string]uint{"cpu": 2, "db": 1})
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if actual := l.Available("cpu"); actual != 2 {
+		t.Errorf("Expected '%d' cpu available, got '%d'", 2, actual)
+	}
+	if _, ok := l.TryAcquire(map[string]uint{"cpu": 1, "db": 1}); ok {
+		t.Fatal("Expected no resources while db is held, got some")
+	}
+	if actual := l.Available("cpu"); actual != 2 {
+		t.Errorf("Expected failed acquisition to take nothing, got '%d' cpu available", actual)
+	}
+
+	if err := s.Release(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err := s.Release(); !errors.Is(err, ErrResourcesReleased) {
+		t.Errorf("Expected '%v', got '%v'", ErrResourcesReleased, err)
+	}
+	if actual := l.Available("cpu"); actual != 4 {
+		t.Errorf("Expected '%d' cpu available, got '%d'", 4, actual)
+	}
+	if actual := l.Available("db"); actual != 1 {
+		t.Errorf("Expected '%d' db available, got '%d'", 1, actual)
+	}
+}
+
+func TestResourceLimiter_InvalidRequest(t *testing.T) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 4})
+
+	if _, err := l.Acquire(context.Background(), map[string]uint{"cpu": 5}); !errors.Is(err, ErrInvalidResourceRequest) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidResourceRequest, err)
+	}
+	if _, err := l.Acquire(context.Background(), map[string]uint{"gpu": 1}); !errors.Is(err, ErrInvalidResourceRequest) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidResourceRequest, err)
+	}
+	if _, err := BuildResourceLimiter(WithResources(map[string]uint{"cpu": 0})); !errors.Is(err, ErrInvalidResources) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidResources, err)
+	}
+}
+
+func TestResourceLimiter_FIFO(t *testing.T) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 4})
+	held, _ := l.TryAcquire(map[string]uint{"cpu": 3})
+
+	large := make(chan *ResourceSet)
+	go func() {
+		s, _ := l.Acquire(context.Background(), map[string]uint{"cpu": 4})
+		large <- s
+	}()
+	waitForResourceWaiters(l, 1)
+
+	// a small request which would fit must not overtake the waiting large one
+	if _, ok := l.TryAcquire(map[string]uint{"cpu": 1}); ok {
+		t.Fatal("Expected small request to wait behind large one")
+	}
+	small := make(chan *ResourceSet)
+	go func() {
+		s, _ := l.Acquire(context.Background(), map[string]uint{"cpu": 1})
+		small <- s
+	}()
+	waitForResourceWaiters(l, 2)
+
+	held.Release()
+	s := <-large
+	select {
+	case <-small:
+		t.Fatal("Expected small request to wait for large one")
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.Release()
+	(<-small).Release()
+}
+
+func TestResourceLimiter_Cancel(t *testing.T) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 2})
+	held, _ := l.TryAcquire(map[string]uint{"cpu": 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		_, err := l.Acquire(ctx, map[string]uint{"cpu": 2})
+		result <- err
+	}()
+	waitForResourceWaiters(l, 1)
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+
+	// the cancelled request no longer holds back others
+	s, ok := l.TryAcquire(map[string]uint{"cpu": 1})
+	if !ok {
+		t.Fatal("Expected resources after cancelled request, got none")
+	}
+	s.Release()
+	held.Release()
+	if actual := l.Available("cpu"); actual != 2 {
+		t.Errorf("Expected '%d' cpu available, got '%d'", 2, actual)
+	}
+}
+
+func TestResourceLimiter_Invoke(t *testing.T) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 2, "io": 1})
+
+	err := l.Invoke(context.Background(), map[string]uint{"cpu": 2, "io": 1}, func() error {
+		if l.Available("cpu") != 0 || l.Available("io") != 0 {
+			t.Error("Expected resources to be held during invocation")
+		}
+		return errors.New("error")
+	})
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if l.Available("cpu") != 2 || l.Available("io") != 1 {
+		t.Error("Expected resources to be released after invocation")
+	}
+}
+
+func waitForResourceWaiters(l *ResourceLimiter, n int) {
+	for {
+		l.mu.Lock()
+		waiting := len(l.waiters)
+		l.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func BenchmarkResourceLimiter(b *testing.B) {
+	l := NewResourceLimiter(map[string]uint{"cpu": 4, "db": 1})
+	request := map[string]uint{"cpu": 2, "db": 1}
+	for i := 0; i < b.N; i++ {
+		s, _ := l.Acquire(context.Background(), request)
+		s.Release()
+	}
+}
@@ -0,0 +1,289 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("Schedule rule is invalid.")
+
+/*
+ScheduleRule applies a rate during a time range on certain days of the week.
+
+Start and End are offsets from midnight. If End is not after Start, the range wraps past midnight, so a rule for Friday from 22:00 to 06:00 applies until 06:00 on Saturday. If Start and End are equal, the rule applies for the whole day. An empty Days applies the rule every day.
+*/
+type ScheduleRule struct {
+	Days  []time.Weekday
+	Start time.Duration
+	End   time.Duration
+	Rate  Rate
+}
+
+/*
+ParseScheduleRule parses a rule specification of an optional day range or list followed by a time range, such as "Mon-Fri 09:00-17:00", "Sat,Sun 00:00-00:00" or "22:00-06:00", and returns a rule which applies the provided rate.
+
+An error wrapping ErrInvalidSchedule is returned if the specification cannot be parsed.
+*/
+func ParseScheduleRule(spec string, rate Rate) (r ScheduleRule, err error) {
+	r.Rate = rate
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+	case 2:
+		if r.Days, err = parseWeekdays(fields[0]); err != nil {
+			err = fmt.Errorf("%w: %q has invalid days", ErrInvalidSchedule, spec)
+			return
+		}
+	default:
+		err = fmt.Errorf("%w: %q is not in the form [days] start-end", ErrInvalidSchedule, spec)
+		return
+	}
+	times := strings.SplitN(fields[len(fields)-1], "-", 2)
+	if len(times) != 2 {
+		err = fmt.Errorf("%w: %q is not in the form [days] start-end", ErrInvalidSchedule, spec)
+		return
+	}
+	if r.Start, err = parseTimeOfDay(times[0]); err == nil {
+		r.End, err = parseTimeOfDay(times[1])
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %q has an invalid time", ErrInvalidSchedule, spec)
+	}
+	return
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWeekdays parses a comma-separated list of weekday names and ranges, like "Mon-Fri" or "Sat,Sun". Ranges may wrap past Saturday.
+func parseWeekdays(s string) (days []time.Weekday, err error) {
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[from]
+		last := first
+		if isRange {
+			var lastOk bool
+			last, lastOk = weekdayNames[to]
+			ok = ok && lastOk
+		}
+		if !ok {
+			err = ErrInvalidSchedule
+			return
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	return
+}
+
+// parseTimeOfDay parses a time like "09:00" or "17:30:15" as an offset from midnight.
+func parseTimeOfDay(s string) (d time.Duration, err error) {
+	var h, m, sec int
+	n, _ := fmt.Sscanf(s, "%d:%d:%d", &h, &m, &sec)
+	if n < 2 || h < 0 || h > 24 || m < 0 || m > 59 || sec < 0 || sec > 59 {
+		err = ErrInvalidSchedule
+		return
+	}
+	d = time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	if d > 24*time.Hour {
+		err = ErrInvalidSchedule
+	}
+	return
+}
+
+/*
+Validate returns an error wrapping ErrInvalidSchedule if the rule's times are not within a day, or ErrInvalidRate if its rate is invalid.
+*/
+func (r ScheduleRule) Validate() error {
+	if r.Start < 0 || r.Start > 24*time.Hour || r.End < 0 || r.End > 24*time.Hour {
+		return fmt.Errorf("%w: start and end must be within a day", ErrInvalidSchedule)
+	}
+	return r.Rate.Validate()
+}
+
+func (r ScheduleRule) onDay(d time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, day := range r.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the rule applies at the time. Offsets are compared with the wall clock time, so rules keep their times of day across daylight saving changes.
+func (r ScheduleRule) contains(t time.Time) bool {
+	h, m, s := t.Clock()
+	offset := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+	day := t.Weekday()
+	switch {
+	case r.Start < r.End:
+		return r.onDay(day) && offset >= r.Start && offset < r.End
+	case r.Start == r.End:
+		return r.onDay(day)
+	}
+	return r.onDay(day) && offset >= r.Start || r.onDay((day+6)%7) && offset < r.End
+}
+
+/*
+ScheduledLimiter switches the rate of an AdjustableRateLimiter according to a schedule of rules, and satisfies the RateLimiter and InvocationLimiter interfaces.
+
+The first rule which applies at the current time determines the rate, and the default rate is used when no rule applies. The rate is switched lazily, when CheckWait or Invoke is called after a schedule boundary, through the limiter's SetMaxRate method, so usage within the current interval or budget is preserved.
+*/
+type ScheduledLimiter struct {
+	mu          sync.Mutex
+	clock       Clock
+	location    *time.Location
+	limiter     AdjustableRateLimiter
+	defaultRate Rate
+	rules       []ScheduleRule
+	current     Rate
+	validUntil  time.Time
+	panicPolicy PanicPolicy
+}
+
+/*
+NewScheduledLimiter instantiates a new ScheduledLimiter which sets the rate of the provided limiter according to the rules, or the default rate when no rule applies.
+
+WithLocation sets the time zone in which the rules' days and times are interpreted, which defaults to UTC. It panics if the default rate, a rule or the options are invalid; BuildScheduledLimiter returns the error instead.
+*/
+func NewScheduledLimiter(l AdjustableRateLimiter, defaultRate Rate, rules []ScheduleRule, opts ...Option) (sl *ScheduledLimiter) {
+	sl, err := BuildScheduledLimiter(l, defaultRate, rules, opts...)
+	must(err)
+	return
+}
+
+/*
+BuildScheduledLimiter instantiates a new ScheduledLimiter as NewScheduledLimiter does, or returns an error if the default rate, a rule or an option is invalid.
+*/
+func BuildScheduledLimiter(l AdjustableRateLimiter, defaultRate Rate, rules []ScheduleRule, opts ...Option) (sl *ScheduledLimiter, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithLocation"), o.validateClock(), defaultRate.Validate()); err != nil {
+		return
+	}
+	if o.locationSet && o.location == nil {
+		err = &OptionError{"WithLocation", ErrNilLocation}
+		return
+	}
+	for _, r := range rules {
+		if err = r.Validate(); err != nil {
+			return
+		}
+	}
+	sl = &ScheduledLimiter{
+		clock:       o.clock,
+		location:    o.location,
+		limiter:     l,
+		defaultRate: defaultRate,
+		rules:       rules,
+		panicPolicy: o.panicPolicy,
+	}
+	if sl.location == nil {
+		sl.location = time.UTC
+	}
+	sl.update()
+	return
+}
+
+/*
+CheckWait applies the scheduled rate if a schedule boundary has passed, and then delegates to the underlying limiter.
+*/
+func (l *ScheduledLimiter) CheckWait() {
+	l.update()
+	l.limiter.CheckWait()
+}
+
+/*
+Invoke enforces the scheduled rate before the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *ScheduledLimiter) Invoke(f func() error) (err error) {
+	l.CheckWait()
+	return l.panicPolicy.invoke(f, nil)
+}
+
+/*
+Rate returns the rate applied at the current time.
+*/
+func (l *ScheduledLimiter) Rate() Rate {
+	l.update()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+/*
+NextChange returns the time of the next schedule boundary, at which the rate may change.
+*/
+func (l *ScheduledLimiter) NextChange() time.Time {
+	l.update()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.validUntil
+}
+
+func (l *ScheduledLimiter) update() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now().In(l.location)
+	if now.Before(l.validUntil) {
+		return
+	}
+	rate := l.rateAt(now)
+	l.validUntil = l.nextBoundary(now)
+	if rate != l.current {
+		l.current = rate
+		l.limiter.SetMaxRate(rate)
+	}
+}
+
+func (l *ScheduledLimiter) rateAt(t time.Time) Rate {
+	for _, r := range l.rules {
+		if r.contains(t) {
+			return r.Rate
+		}
+	}
+	return l.defaultRate
+}
+
+// nextBoundary returns the earliest rule start or end time after the provided time, within the next week.
+func (l *ScheduledLimiter) nextBoundary(t time.Time) (next time.Time) {
+	y, m, d := t.Date()
+	for i := 0; i <= 7; i += 1 {
+		for _, r := range l.rules {
+			for _, offset := range []time.Duration{0, r.Start, r.End} {
+				b := atTimeOfDay(y, m, d+i, offset, t.Location())
+				if b.After(t) && (next.IsZero() || b.Before(next)) {
+					next = b
+				}
+			}
+		}
+		if !next.IsZero() {
+			return
+		}
+	}
+	return t.Add(7 * 24 * time.Hour)
+}
+
+// atTimeOfDay returns the wall clock time at the offset from midnight on the date, which is not the same as adding the offset to midnight on days when daylight saving time changes.
+func atTimeOfDay(y int, m time.Month, d int, offset time.Duration, loc *time.Location) time.Time {
+	h := offset / time.Hour
+	mins := offset % time.Hour / time.Minute
+	sec := offset % time.Minute / time.Second
+	return time.Date(y, m, d, int(h), int(mins), int(sec), 0, loc)
+}
@@ -0,0 +1,170 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleRule(t *testing.T) {
+	rate := NewRate(50, time.Second)
+	tests := map[string]ScheduleRule{
+		"Mon-Fri 09:00-17:00": {[]time.Weekday{1, 2, 3, 4, 5}, 9 * time.Hour, 17 * time.Hour, rate},
+		"sat,sun 00:00-00:00": {[]time.Weekday{6, 0}, 0, 0, rate},
+		"Fri-Mon 22:00-06:30": {[]time.Weekday{5, 6, 0, 1}, 22 * time.Hour, 6*time.Hour + 30*time.Minute, rate},
+		"12:00:30-24:00":      {nil, 12*time.Hour + 30*time.Second, 24 * time.Hour, rate},
+	}
+	for spec, expected := range tests {
+		r, err := ParseScheduleRule(spec, rate)
+		if err != nil {
+			t.Errorf("%q: unexpected error, got: %s", spec, err.Error())
+			continue
+		}
+		if len(r.Days) != len(expected.Days) || r.Start != expected.Start || r.End != expected.End || r.Rate != rate {
+			t.Errorf("%q: expected %v, got %v", spec, expected, r)
+			continue
+		}
+		for i := range r.Days {
+			if r.Days[i] != expected.Days[i] {
+				t.Errorf("%q: expected %v, got %v", spec, expected.Days, r.Days)
+			}
+		}
+	}
+	for _, spec := range []string{"", "Mon", "Mon 09:00", "Xyz 09:00-10:00", "Mon 9-10", "Mon 25:00-26:00", "a b c"} {
+		if _, err := ParseScheduleRule(spec, rate); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("%q: expected '%v', got '%v'", spec, ErrInvalidSchedule, err)
+		}
+	}
+}
+
+func TestScheduledLimiter(t *testing.T) {
+	business, _ := ParseScheduleRule("Mon-Fri 09:00-17:00", NewRate(50, time.Second))
+	weekend, _ := ParseScheduleRule("Sat,Sun 00:00-00:00", NewRate(100, time.Second))
+	overnight := NewRate(500, time.Second)
+
+	// Friday at 16:59:59.5
+	c := NewFakeClock(time.Date(2019, 1, 4, 16, 59, 59, 500000000, time.UTC))
+	bl := NewBurstRateLimiter(NewRate(1, time.Second), WithClock(c))
+	l := NewScheduledLimiter(bl, overnight, []ScheduleRule{business, weekend}, WithClock(c))
+
+	if r := l.Rate(); r != business.Rate {
+		t.Fatalf("Expected %v, got %v", business.Rate, r)
+	}
+	if next := l.NextChange(); !next.Equal(time.Date(2019, 1, 4, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next change at 17:00, got %s", next)
+	}
+	for i := 0; i < 50; i += 1 {
+		l.CheckWait()
+	}
+	if bl.Allow() {
+		t.Fatal("Expected business hours rate to be exhausted")
+	}
+
+	// the overnight rate applies without resetting usage in the current interval
+	c.Advance(500 * time.Millisecond)
+	l.CheckWait()
+	if bl.maxCount != 500 || bl.count != 51 {
+		t.Errorf("Expected max 500 with usage 51, got max %d with usage %d", bl.maxCount, bl.count)
+	}
+
+	tests := []struct {
+		at       time.Time
+		expected Rate
+	}{
+		{time.Date(2019, 1, 5, 0, 0, 0, 0, time.UTC), weekend.Rate},
+		{time.Date(2019, 1, 6, 23, 59, 0, 0, time.UTC), weekend.Rate},
+		{time.Date(2019, 1, 7, 0, 0, 0, 0, time.UTC), overnight},
+		{time.Date(2019, 1, 7, 9, 0, 0, 0, time.UTC), business.Rate},
+	}
+	for _, test := range tests {
+		c.Advance(test.at.Sub(c.Now()))
+		if r := l.Rate(); r != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.at, test.expected, r)
+		}
+		if bl.maxCount != test.expected.Count {
+			t.Errorf("%s: expected limiter max %d, got %d", test.at, test.expected.Count, bl.maxCount)
+		}
+	}
+}
+
+func TestScheduledLimiter_Overnight(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	night, _ := ParseScheduleRule("Fri 22:00-06:00", NewRate(500, time.Second))
+	day := NewRate(50, time.Second)
+
+	// Friday at 21:00 in New York
+	c := NewFakeClock(time.Date(2019, 1, 5, 2, 0, 0, 0, time.UTC))
+	bucket := NewBucketRateLimiter(day, 0, WithClock(c))
+	l := NewScheduledLimiter(bucket, day, []ScheduleRule{night}, WithClock(c), WithLocation(ny))
+
+	tests := []struct {
+		advance  time.Duration
+		expected Rate
+	}{
+		{0, day},
+		{time.Hour, night.Rate},
+		{7 * time.Hour, night.Rate},
+		{time.Hour, day},
+		{6 * 24 * time.Hour, day},
+		{16 * time.Hour, night.Rate},
+	}
+	for _, test := range tests {
+		c.Advance(test.advance)
+		if r := l.Rate(); r != test.expected {
+			t.Errorf("%s: expected %v, got %v", c.Now().In(ny), test.expected, r)
+		}
+	}
+}
+
+func TestScheduledLimiter_DaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	business, _ := ParseScheduleRule("09:00-17:00", NewRate(50, time.Second))
+	night := NewRate(500, time.Second)
+
+	// clocks go forward at 02:00 on Sunday 10 March 2019, so the day is 23 hours long
+	c := NewFakeClock(time.Date(2019, 3, 10, 1, 0, 0, 0, ny))
+	l := NewScheduledLimiter(NewBurstRateLimiter(night, WithClock(c)), night, []ScheduleRule{business}, WithClock(c), WithLocation(ny))
+
+	if next := l.NextChange(); !next.Equal(time.Date(2019, 3, 10, 9, 0, 0, 0, ny)) {
+		t.Errorf("Expected next change at 09:00, got %s", next.In(ny))
+	}
+	c.Advance(time.Date(2019, 3, 10, 9, 0, 0, 0, ny).Sub(c.Now()))
+	if r := l.Rate(); r != business.Rate {
+		t.Errorf("Expected %v at 09:00, got %v", business.Rate, r)
+	}
+	c.Advance(time.Date(2019, 3, 10, 16, 30, 0, 0, ny).Sub(c.Now()))
+	if r := l.Rate(); r != business.Rate {
+		t.Errorf("Expected %v at 16:30, got %v", business.Rate, r)
+	}
+	if next := l.NextChange(); !next.Equal(time.Date(2019, 3, 10, 17, 0, 0, 0, ny)) {
+		t.Errorf("Expected next change at 17:00, got %s", next.In(ny))
+	}
+}
+
+func TestBuildScheduledLimiter_Invalid(t *testing.T) {
+	bl := NewBurstRateLimiter(NewRate(1, time.Second))
+	rule := ScheduleRule{Start: 25 * time.Hour, End: time.Hour, Rate: NewRate(1, time.Second)}
+	tests := []struct {
+		defaultRate Rate
+		rules       []ScheduleRule
+		opts        []Option
+		expected    error
+	}{
+		{Rate{}, nil, nil, ErrInvalidRate},
+		{NewRate(1, time.Second), []ScheduleRule{rule}, nil, ErrInvalidSchedule},
+		{NewRate(1, time.Second), nil, []Option{WithLocation(nil)}, ErrNilLocation},
+		{NewRate(1, time.Second), nil, []Option{WithRecheck(time.Second)}, ErrInapplicableOption},
+	}
+	for i, test := range tests {
+		l, err := BuildScheduledLimiter(bl, test.defaultRate, test.rules, test.opts...)
+		if l != nil || !errors.Is(err, test.expected) {
+			t.Errorf("%d: expected '%v', got '%v'", i, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package limiter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrSignalUnavailable = errors.New("Signal is not available on this platform.")
+
+/*
+Signal is the interface that wraps the Sample method, representing a measurement of load such as goroutine count or CPU utilisation.
+
+Sample returns the current value of the signal. Signals which measure change over time may return 0 on their first sample.
+*/
+type Signal interface {
+	Sample() (float64, error)
+}
+
+/*
+SignalFunc is an adapter allowing an ordinary function to be used as a Signal.
+*/
+type SignalFunc func() (float64, error)
+
+func (f SignalFunc) Sample() (float64, error) {
+	return f()
+}
+
+/*
+GoroutineSignal returns a Signal which samples the number of goroutines.
+*/
+func GoroutineSignal() Signal {
+	return SignalFunc(func() (float64, error) {
+		return float64(runtime.NumGoroutine()), nil
+	})
+}
+
+/*
+HeapSignal returns a Signal which samples the bytes of heap memory occupied by live and not-yet-swept objects.
+*/
+func HeapSignal() Signal {
+	return SignalFunc(func() (float64, error) {
+		s := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+		metrics.Read(s)
+		if s[0].Value.Kind() != metrics.KindUint64 {
+			return 0, ErrSignalUnavailable
+		}
+		return float64(s[0].Value.Uint64()), nil
+	})
+}
+
+/*
+GCPauseSignal returns a Signal which samples the fraction of CPU time spent in garbage collection pauses since the previous sample, between 0 and 1.
+*/
+func GCPauseSignal() Signal {
+	return &gcPauseSignal{}
+}
+
+type gcPauseSignal struct {
+	mu        sync.Mutex
+	lastPause float64
+	lastTotal float64
+}
+
+func (s *gcPauseSignal) Sample() (v float64, err error) {
+	samples := []metrics.Sample{
+		{Name: "/cpu/classes/gc/pause:cpu-seconds"},
+		{Name: "/cpu/classes/total:cpu-seconds"},
+	}
+	metrics.Read(samples)
+	for _, sample := range samples {
+		if sample.Value.Kind() != metrics.KindFloat64 {
+			return 0, ErrSignalUnavailable
+		}
+	}
+	pause, total := samples[0].Value.Float64(), samples[1].Value.Float64()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if total > s.lastTotal {
+		v = (pause - s.lastPause) / (total - s.lastTotal)
+	}
+	s.lastPause, s.lastTotal = pause, total
+	return
+}
+
+// clockTicksPerSecond is the unit of CPU times in /proc/self/stat, which is 100 on practically all Linux systems.
+const clockTicksPerSecond = 100
+
+/*
+CPUSignal returns a Signal which samples the process's CPU utilisation since the previous sample, as a fraction of all CPUs between 0 and 1. It reads /proc/self/stat, and returns ErrSignalUnavailable on platforms without it.
+
+Only WithClock applies. It panics if an option is invalid; BuildCPUSignal returns the error instead.
+*/
+func CPUSignal(opts ...Option) (s Signal) {
+	s, err := BuildCPUSignal(opts...)
+	must(err)
+	return
+}
+
+/*
+BuildCPUSignal returns a Signal as CPUSignal does, or returns an *OptionError if an option is invalid or does not apply.
+*/
+func BuildCPUSignal(opts ...Option) (s Signal, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithClock"), o.validateClock()); err != nil {
+		return
+	}
+	s = &cpuSignal{
+		clock: o.clock,
+		path:  "/proc/self/stat",
+		cpus:  runtime.NumCPU(),
+	}
+	return
+}
+
+type cpuSignal struct {
+	mu        sync.Mutex
+	clock     Clock
+	path      string
+	cpus      int
+	lastTicks uint64
+	lastTime  time.Time
+}
+
+func (s *cpuSignal) Sample() (v float64, err error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = ErrSignalUnavailable
+	}
+	if err != nil {
+		return
+	}
+	ticks, err := parseProcStatTicks(b)
+	if err != nil {
+		return
+	}
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elapsed := now.Sub(s.lastTime); !s.lastTime.IsZero() && elapsed > 0 {
+		cpuSeconds := float64(ticks-s.lastTicks) / clockTicksPerSecond
+		v = cpuSeconds / elapsed.Seconds() / float64(s.cpus)
+	}
+	s.lastTicks, s.lastTime = ticks, now
+	return
+}
+
+// parseProcStatTicks returns the sum of the user and system CPU times (fields 14 and 15) of a /proc/[pid]/stat file.
+func parseProcStatTicks(b []byte) (ticks uint64, err error) {
+	// the command name in field 2 may contain spaces, so fields are counted from its closing parenthesis
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, ErrSignalUnavailable
+	}
+	fields := bytes.Fields(b[i+1:])
+	if len(fields) < 13 {
+		return 0, ErrSignalUnavailable
+	}
+	for _, f := range fields[11:13] {
+		n, err := strconv.ParseUint(string(f), 10, 64)
+		if err != nil {
+			return 0, ErrSignalUnavailable
+		}
+		ticks += n
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRuntimeSignals(t *testing.T) {
+	if v, err := GoroutineSignal().Sample(); err != nil || v < 1 {
+		t.Errorf("Expected at least 1 goroutine, got %f (%v)", v, err)
+	}
+	if v, err := HeapSignal().Sample(); err != nil || v <= 0 {
+		t.Errorf("Expected positive heap size, got %f (%v)", v, err)
+	}
+	s := GCPauseSignal()
+	runtime.GC()
+	for i := 0; i < 2; i += 1 {
+		if v, err := s.Sample(); err != nil || v < 0 || v > 1 {
+			t.Errorf("Expected fraction, got %f (%v)", v, err)
+		}
+	}
+}
+
+func TestCPUSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stat")
+	write := func(utime, stime int) {
+		stat := fmt.Sprintf("1234 (my (odd) proc) S 1 1234 1234 0 -1 4194560 100 0 0 0 %d %d 0 0 20 0 4 0 100 0 0", utime, stime)
+		if err := os.WriteFile(path, []byte(stat), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &cpuSignal{clock: c, path: path, cpus: 4}
+
+	write(100, 50)
+	if v, err := s.Sample(); err != nil || v != 0 {
+		t.Fatalf("Expected 0 on first sample, got %f (%v)", v, err)
+	}
+	// 200 ticks is 2 CPU-seconds over 1 second on 4 CPUs
+	write(250, 100)
+	c.Advance(time.Second)
+	if v, err := s.Sample(); err != nil || v != 0.5 {
+		t.Errorf("Expected 0.5, got %f (%v)", v, err)
+	}
+
+	os.WriteFile(path, []byte("garbage"), 0o644)
+	if _, err := s.Sample(); err != ErrSignalUnavailable {
+		t.Errorf("Expected '%v', got '%v'", ErrSignalUnavailable, err)
+	}
+	s.path = filepath.Join(t.TempDir(), "missing")
+	if _, err := s.Sample(); err != ErrSignalUnavailable {
+		t.Errorf("Expected '%v', got '%v'", ErrSignalUnavailable, err)
+	}
+}
+
+func TestBuildCPUSignal_Invalid(t *testing.T) {
+	if _, err := BuildCPUSignal(WithClock(NewFakeClock(time.Now()))); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+	_, err := BuildCPUSignal(WithTokens(1))
+	var optErr *OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "WithTokens" || !errors.Is(err, ErrInapplicableOption) {
+		t.Errorf("Expected '%v' for WithTokens, got '%v'", ErrInapplicableOption, err)
+	}
+	if _, err := BuildCPUSignal(WithClock(nil)); !errors.Is(err, ErrNilClock) {
+		t.Errorf("Expected '%v', got '%v'", ErrNilClock, err)
+	}
+}
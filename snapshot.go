@@ -0,0 +1,130 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+SnapshotStore saves the state of registered StatefulLimiters to a local file and restores it on startup, so limiters remember their usage and failures across restarts.
+
+The file is replaced atomically on each save. Typical use is to register limiters, call Restore, and then call Start to save snapshots periodically, with a final snapshot saved by Stop during shutdown.
+*/
+type SnapshotStore struct {
+	mu       sync.Mutex
+	saveMu   sync.Mutex
+	clock    Clock
+	path     string
+	limiters map[string]StatefulLimiter
+	poll     poller
+}
+
+type snapshotFile struct {
+	Limiters map[string][]byte `json:"limiters"`
+}
+
+/*
+NewSnapshotStore instantiates a new SnapshotStore which saves state in the file at the provided path. The file's directory must exist.
+*/
+func NewSnapshotStore(path string, opts ...Option) (s *SnapshotStore) {
+	s = &SnapshotStore{
+		clock:    newOptions(opts).clock,
+		path:     path,
+		limiters: make(map[string]StatefulLimiter),
+	}
+	return
+}
+
+/*
+Register adds a limiter to the store under the provided name, which identifies its state in the file.
+*/
+func (s *SnapshotStore) Register(name string, l StatefulLimiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiters[name] = l
+}
+
+/*
+SetErrorHandler sets a function which is called with errors from periodic saves.
+*/
+func (s *SnapshotStore) SetErrorHandler(f func(error)) {
+	s.poll.setErrorHandler(f)
+}
+
+/*
+Restore reads the file and restores the state of each registered limiter found in it. It returns nil if the file does not exist.
+
+Limiters without saved state are left unchanged, and saved state for names which are not registered is ignored. Errors restoring individual limiters are joined and returned after the others have been restored.
+*/
+func (s *SnapshotStore) Restore() (err error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	var f snapshotFile
+	if err = json.Unmarshal(b, &f); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, name := range sortedKeys(s.limiters) {
+		state, ok := f.Limiters[name]
+		if !ok {
+			continue
+		}
+		if rErr := s.limiters[name].UnmarshalBinary(state); rErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, rErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+Save writes the current state of every registered limiter to the file. Saves are made one at a time, but do not prevent limiters from being registered.
+*/
+func (s *SnapshotStore) Save() (err error) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.Lock()
+	limiters := make(map[string]StatefulLimiter, len(s.limiters))
+	for name, l := range s.limiters {
+		limiters[name] = l
+	}
+	s.mu.Unlock()
+	f := snapshotFile{
+		Limiters: make(map[string][]byte, len(limiters)),
+	}
+	for name, l := range limiters {
+		if f.Limiters[name], err = l.MarshalBinary(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+/*
+Start begins saving snapshots at the provided interval in a new goroutine. Calling Start on a store which is already saving has no effect. ErrInvalidInterval is returned if the interval is not positive.
+*/
+func (s *SnapshotStore) Start(interval time.Duration) error {
+	return s.poll.start(s.clock, interval, s.Save)
+}
+
+/*
+Stop ends periodic saving, waits for any save in progress to complete, and then saves a final snapshot, returning its error.
+*/
+func (s *SnapshotStore) Stop() error {
+	s.poll.halt()
+	return s.Save()
+}
@@ -0,0 +1,108 @@
+package limiter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	fl := NewFailBackOffLimiter(backoff.None, WithClock(c))
+	il := NewIntervalLimiter(time.Minute, WithClock(c))
+	s := NewSnapshotStore(path, WithClock(c))
+	s.Register("fail", fl)
+	s.Register("interval", il)
+	if err := s.Restore(); err != nil {
+		t.Fatalf("Expected missing file to be ignored, got: %s", err.Error())
+	}
+
+	errs := make(chan error, 1)
+	s.SetErrorHandler(func(err error) { errs <- err })
+	s.Start(time.Second)
+	fl.Report(false)
+	il.CheckWait()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	c.BlockUntil(1)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected periodic snapshot, got: %s", err.Error())
+	}
+	fl.Report(false)
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+
+	c.Advance(10 * time.Second)
+	restoredFl := NewFailBackOffLimiter(backoff.None, WithClock(c))
+	restoredIl := NewIntervalLimiter(time.Minute, WithClock(c))
+	restored := NewSnapshotStore(path, WithClock(c))
+	restored.Register("fail", restoredFl)
+	restored.Register("interval", restoredIl)
+	restored.Register("new", NewFailBackOffLimiter(backoff.None))
+	if err := restored.Restore(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if restoredFl.failCount != 2 {
+		t.Errorf("Expected 2, got %d", restoredFl.failCount)
+	}
+
+	done := make(chan struct{})
+	start := c.Now()
+	go func() {
+		restoredIl.CheckWait()
+		close(done)
+	}()
+	advanceWhileSleeping(c, time.Second, done)
+	if waited := c.Now().Sub(start); waited != 49*time.Second {
+		t.Errorf("Expected remaining interval of 49s after downtime, got %s", waited)
+	}
+
+	mismatched := NewSnapshotStore(path)
+	mismatched.Register("fail", NewIntervalLimiter(time.Minute))
+	if err := mismatched.Restore(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidState, err)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	default:
+	}
+}
+
+func TestSnapshotStore_SaveWhileWaiting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	il := NewIntervalLimiter(time.Hour, WithClock(c))
+	fil := NewFixedIntervalLimiter(time.Hour, WithClock(c))
+	s := NewSnapshotStore(path, WithClock(c))
+	s.Register("interval", il)
+	s.Register("fixed", fil)
+
+	// a caller of each limiter is waiting for the next interval
+	il.CheckWait()
+	fil.CheckWait()
+	go il.CheckWait()
+	go fil.CheckWait()
+	c.BlockUntil(2)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Save()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error, got: %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Save not to wait for callers waiting on the limiters")
+	}
+	c.Advance(time.Hour)
+}
@@ -0,0 +1,133 @@
+package limiter
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrInvalidState = errors.New("Limiter state is invalid or belongs to a different type of limiter.")
+
+// stateVersion is the first byte of every encoded limiter state.
+const stateVersion = 1
+
+// stateKind is the second byte of every encoded limiter state, identifying the type of limiter which produced it.
+type stateKind byte
+
+const (
+	failBackOffState stateKind = iota + 1
+	failRateState
+	burstRateState
+	bucketRateState
+	intervalState
+	fixedIntervalState
+)
+
+// stateEncoder appends limiter state fields to a buffer. Times are encoded as wall clock nanoseconds, so that a restored limiter accounts for the time which elapsed while the process was down.
+type stateEncoder struct {
+	b []byte
+}
+
+func newStateEncoder(kind stateKind) *stateEncoder {
+	return &stateEncoder{b: []byte{stateVersion, byte(kind)}}
+}
+
+func (e *stateEncoder) uint(v uint64) {
+	e.b = binary.AppendUvarint(e.b, v)
+}
+
+func (e *stateEncoder) int(v int64) {
+	e.b = binary.AppendVarint(e.b, v)
+}
+
+func (e *stateEncoder) float(v float64) {
+	e.uint(math.Float64bits(v))
+}
+
+func (e *stateEncoder) time(t time.Time) {
+	if t.IsZero() {
+		e.int(0)
+		return
+	}
+	e.int(t.UnixNano())
+}
+
+func (e *stateEncoder) bytes(b []byte) {
+	e.uint(uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// stateDecoder reads limiter state fields from a buffer, recording the first error so fields can be read without checking each one.
+type stateDecoder struct {
+	b   []byte
+	err error
+}
+
+func newStateDecoder(b []byte, kind stateKind) (d *stateDecoder) {
+	d = &stateDecoder{b: b}
+	if len(b) < 2 || b[0] != stateVersion || stateKind(b[1]) != kind {
+		d.err = ErrInvalidState
+		return
+	}
+	d.b = b[2:]
+	return
+}
+
+func (d *stateDecoder) uint() (v uint64) {
+	if d.err != nil {
+		return
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrInvalidState
+		return 0
+	}
+	d.b = d.b[n:]
+	return
+}
+
+func (d *stateDecoder) int() (v int64) {
+	if d.err != nil {
+		return
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = ErrInvalidState
+		return 0
+	}
+	d.b = d.b[n:]
+	return
+}
+
+func (d *stateDecoder) float() float64 {
+	return math.Float64frombits(d.uint())
+}
+
+func (d *stateDecoder) time() (t time.Time) {
+	if ns := d.int(); ns != 0 {
+		t = time.Unix(0, ns)
+	}
+	return
+}
+
+func (d *stateDecoder) bytes() (b []byte) {
+	n := d.uint()
+	if d.err != nil {
+		return
+	}
+	if n > uint64(len(d.b)) {
+		d.err = ErrInvalidState
+		return
+	}
+	b, d.b = d.b[:n], d.b[n:]
+	return
+}
+
+// finish returns the first decoding error, or ErrInvalidState if unread bytes remain.
+func (d *stateDecoder) finish() error {
+	if d.err == nil && len(d.b) > 0 {
+		d.err = ErrInvalidState
+	}
+	return d.err
+}
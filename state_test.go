@@ -0,0 +1,77 @@
+package limiter
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestStatefulLimiters(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	fl := NewFailBackOffLimiter(backoff.None, WithClock(c))
+	fl.Report(false)
+	fl.Report(false)
+	restoredFl := NewFailBackOffLimiter(backoff.None, WithClock(c))
+	roundTrip(t, fl, restoredFl)
+	if restoredFl.failCount != 2 {
+		t.Errorf("Expected 2, got %d", restoredFl.failCount)
+	}
+
+	bl := NewBurstRateLimiter(NewRate(2, time.Second), WithClock(c))
+	bl.Allow()
+	bl.Allow()
+	restoredBl := NewBurstRateLimiter(NewRate(2, time.Second), WithClock(c))
+	roundTrip(t, bl, restoredBl)
+	if restoredBl.Allow() {
+		t.Error("Expected restored interval to be exhausted")
+	}
+
+	bucket := NewBucketRateLimiter(NewRate(10, time.Second), 10, WithClock(c))
+	bucket.CheckWaitN(10)
+	restoredBucket := NewBucketRateLimiter(NewRate(10, time.Second), 10, WithClock(c))
+	roundTrip(t, bucket, restoredBucket)
+	c.Advance(500 * time.Millisecond)
+	if restoredBucket.reserve(0); math.Abs(restoredBucket.available-5) > 1e-9 {
+		t.Errorf("Expected budget of 5 after downtime, got %f", restoredBucket.available)
+	}
+
+	il := NewIntervalLimiter(time.Minute, WithClock(c))
+	il.CheckWait()
+	restoredIl := NewIntervalLimiter(time.Minute, WithClock(c))
+	roundTrip(t, il, restoredIl)
+	if !restoredIl.last.Equal(il.last) {
+		t.Errorf("Expected %s, got %s", il.last, restoredIl.last)
+	}
+
+	frl := NewFailRateLimiter(NewRate(5, time.Second), backoff.None, WithClock(c))
+	frl.Report(false)
+	restoredFrl := NewFailRateLimiter(NewRate(5, time.Second), backoff.None, WithClock(c))
+	roundTrip(t, frl, restoredFrl)
+	if n := restoredFrl.failLimiter.(*FailBackOffLimiter).failCount; n != 1 {
+		t.Errorf("Expected 1, got %d", n)
+	}
+
+	for _, b := range [][]byte{nil, {stateVersion}, {stateVersion, byte(burstRateState)}, {stateVersion, byte(failBackOffState), 1, 2}} {
+		if err := restoredFl.UnmarshalBinary(b); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%v: expected '%v', got '%v'", b, ErrInvalidState, err)
+		}
+	}
+	if restoredFl.failCount != 2 {
+		t.Errorf("Expected invalid state to make no changes, got %d", restoredFl.failCount)
+	}
+}
+
+func roundTrip(t *testing.T, from, to StatefulLimiter) {
+	t.Helper()
+	b, err := from.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err = to.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+}
@@ -0,0 +1,160 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidTickRate = errors.New("Rate must leave at least one nanosecond between permits.")
+
+/*
+PermitTicker delivers permits on a channel at a configured rate, for select-driven code which cannot call a blocking CheckWait. It also satisfies the RateLimiter interface, where CheckWait blocks until a permit is received.
+
+Permits which are not received accumulate up to the burst size; further permits are dropped until there is room, as with a time.Ticker.
+*/
+type PermitTicker struct {
+	permits   chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+	stopWaits bool
+}
+
+/*
+NewPermitTicker instantiates a new PermitTicker which delivers permits at the provided rate, evenly spaced.
+
+It panics with an *OptionError if the rate or options are invalid; BuildPermitTicker returns the error instead.
+*/
+func NewPermitTicker(r Rate, opts ...Option) (t *PermitTicker) {
+	t, err := BuildPermitTicker(append([]Option{WithRate(r)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildPermitTicker instantiates a new PermitTicker configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithRate is required, and its interval between permits must not round down to zero, so rates faster than one permit per nanosecond are rejected. WithBurst sets the number of permits which can accumulate, which defaults to 1; the ticker starts with a full burst available.
+*/
+func BuildPermitTicker(opts ...Option) (t *PermitTicker, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRate", "WithBurst"), o.validateClock(), o.validateRate()); err != nil {
+		return
+	}
+	if o.rate.Interval() <= 0 {
+		err = &OptionError{"WithRate", ErrInvalidTickRate}
+		return
+	}
+	if o.burst < 0 {
+		err = &OptionError{"WithBurst", ErrInvalidBurst}
+		return
+	}
+	burst := o.burst
+	if burst == 0 {
+		burst = 1
+	}
+	t = newPermitTicker(burst)
+	t.stopWaits = true
+	for i := 0; i < burst; i += 1 {
+		t.permits <- struct{}{}
+	}
+	go t.tick(o.clock, o.rate.Interval())
+	return
+}
+
+/*
+NewRateLimiterTicker instantiates a new PermitTicker which delivers a permit each time the provided RateLimiter's CheckWait returns, adapting limiters such as BurstRateLimiter or IntervalLimiter to select-driven code.
+
+One permit is acquired from the limiter ahead of being received. The permit channel is unbuffered, so permits do not accumulate.
+*/
+func NewRateLimiterTicker(l RateLimiter) (t *PermitTicker) {
+	t = newPermitTicker(0)
+	go t.adapt(l)
+	return
+}
+
+func newPermitTicker(burst int) *PermitTicker {
+	return &PermitTicker{
+		permits: make(chan struct{}, burst),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+/*
+Permits returns the channel on which permits are delivered. It is not closed by Stop, so that receiving from it in a select never yields a spurious permit.
+*/
+func (t *PermitTicker) Permits() <-chan struct{} {
+	return t.permits
+}
+
+/*
+CheckWait blocks until a permit is received. Once the ticker is stopped, it receives any permits which have accumulated and then returns immediately, without a permit, rather than blocking forever.
+*/
+func (t *PermitTicker) CheckWait() {
+	select {
+	case <-t.permits:
+		return
+	default:
+	}
+	select {
+	case <-t.permits:
+	case <-t.stop:
+	}
+}
+
+/*
+Stop ends the delivery of permits and waits for the ticker's timer to be released. Permits which have accumulated may still be received, and CheckWait calls blocked or made after Stop return once they are used up.
+
+A ticker created by NewRateLimiterTicker stops once the CheckWait call in progress returns; Stop does not wait for it.
+*/
+func (t *PermitTicker) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	if t.stopWaits {
+		<-t.done
+	}
+}
+
+func (t *PermitTicker) tick(clock Clock, interval time.Duration) {
+	defer close(t.done)
+	next := clock.Now().Add(interval)
+	for {
+		timer := clock.NewTimer(next.Sub(clock.Now()))
+		select {
+		case <-t.stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		select {
+		case t.permits <- struct{}{}:
+		default:
+		}
+		// permits are scheduled from the previous deadline, so late wake-ups do not slow the rate,
+		// but a ticker which has fallen far behind does not try to catch up
+		next = next.Add(interval)
+		if now := clock.Now(); next.Before(now) {
+			next = now
+		}
+	}
+}
+
+func (t *PermitTicker) adapt(l RateLimiter) {
+	defer close(t.done)
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+		l.CheckWait()
+		select {
+		case <-t.stop:
+			return
+		case t.permits <- struct{}{}:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func expectPermit(t *testing.T, pt *PermitTicker) {
+	t.Helper()
+	select {
+	case <-pt.Permits():
+	case <-time.After(time.Second):
+		t.Fatal("Expected permit, got none")
+	}
+}
+
+func expectNoPermit(t *testing.T, pt *PermitTicker) {
+	t.Helper()
+	select {
+	case <-pt.Permits():
+		t.Fatal("Expected no permit, got one")
+	default:
+	}
+}
+
+func TestPermitTicker(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	pt := NewPermitTicker(NewRate(2, time.Second), WithClock(c), WithBurst(2))
+	defer pt.Stop()
+
+	expectPermit(t, pt)
+	expectPermit(t, pt)
+	expectNoPermit(t, pt)
+
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+	expectPermit(t, pt)
+	expectNoPermit(t, pt)
+
+	// permits which are not received accumulate up to the burst size
+	for i := 0; i < 3; i += 1 {
+		c.BlockUntil(1)
+		c.Advance(500 * time.Millisecond)
+	}
+	c.BlockUntil(1)
+	expectPermit(t, pt)
+	expectPermit(t, pt)
+	expectNoPermit(t, pt)
+}
+
+func TestPermitTicker_Stop(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	pt := NewPermitTicker(NewRate(1, time.Second), WithClock(c))
+
+	c.BlockUntil(1)
+	pt.Stop()
+	if actual := c.Sleepers(); actual != 0 {
+		t.Errorf("Expected timer to be released, got '%d' sleepers", actual)
+	}
+	expectPermit(t, pt)
+	pt.Stop()
+}
+
+func TestPermitTicker_CheckWait(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	pt := NewPermitTicker(NewRate(1, time.Second), WithClock(c))
+	defer pt.Stop()
+
+	pt.CheckWait()
+	done := make(chan struct{})
+	go func() {
+		pt.CheckWait()
+		close(done)
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	<-done
+}
+
+func TestPermitTicker_CheckWaitAfterStop(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	pt := NewPermitTicker(NewRate(1, time.Second), WithClock(c))
+
+	pt.CheckWait()
+	done := make(chan struct{})
+	go func() {
+		pt.CheckWait()
+		close(done)
+	}()
+	c.BlockUntil(1)
+	pt.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected waiting CheckWait to return after Stop")
+	}
+	// without accumulated permits, later calls do not block either
+	pt.CheckWait()
+}
+
+func TestBuildPermitTicker(t *testing.T) {
+	if _, err := BuildPermitTicker(); !errors.Is(err, ErrMissingOption) {
+		t.Errorf("Expected '%v', got '%v'", ErrMissingOption, err)
+	}
+	if _, err := BuildPermitTicker(WithRate(NewRate(1, time.Second)), WithBurst(-1)); !errors.Is(err, ErrInvalidBurst) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidBurst, err)
+	}
+	if _, err := BuildPermitTicker(WithRate(NewRate(2e9, time.Second))); !errors.Is(err, ErrInvalidTickRate) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidTickRate, err)
+	}
+}
+
+func TestRateLimiterTicker(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	l := NewFixedIntervalLimiter(time.Second, WithClock(c))
+	pt := NewRateLimiterTicker(l)
+
+	expectPermit(t, pt)
+	c.BlockUntil(1)
+	expectNoPermit(t, pt)
+	c.Advance(time.Second)
+	expectPermit(t, pt)
+
+	pt.Stop()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	select {
+	case <-pt.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected ticker to stop after the limiter's wait")
+	}
+}
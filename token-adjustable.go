@@ -12,17 +12,55 @@ type AdjustableTokenChanLimiter struct {
 	TokenChanLimiter
 	maxTokenCount uint
 	tokenCount    uint
+	retiring      uint
 }
 
-func NewAdjustableTokenChanLimiter(initialTokens uint, maxTokens uint) (tl *AdjustableTokenChanLimiter) {
+/*
+NewAdjustableTokenChanLimiter instantiates a new AdjustableTokenChanLimiter with the provided initial and maximum number of tokens.
+
+The token counts are not validated, for compatibility with earlier versions: initial tokens are only added up to the maximum. BuildAdjustableTokenChanLimiter rejects a maximum of 0 or an initial number of tokens above the maximum. NewAdjustableTokenChanLimiter panics with an *OptionError only if an option is invalid.
+*/
+func NewAdjustableTokenChanLimiter(initialTokens uint, maxTokens uint, opts ...Option) (tl *AdjustableTokenChanLimiter) {
+	o := newOptions(opts)
+	must(o.validateApplicable())
+	return newAdjustableTokenChanLimiter(initialTokens, maxTokens, o)
+}
+
+/*
+BuildAdjustableTokenChanLimiter instantiates a new AdjustableTokenChanLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithMaxTokens is required. WithTokens sets the initial number of tokens, which defaults to 0 and must not exceed the maximum.
+*/
+func BuildAdjustableTokenChanLimiter(opts ...Option) (tl *AdjustableTokenChanLimiter, err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens", "WithMaxTokens"); err != nil {
+		return
+	}
+	if o.maxTokens == 0 {
+		err = &OptionError{"WithMaxTokens", ErrInvalidTokenCount}
+		return
+	}
+	if o.tokens > o.maxTokens {
+		err = &OptionError{"WithTokens", ErrTokenCountExceedsMax}
+		return
+	}
+	tl = newAdjustableTokenChanLimiter(o.tokens, o.maxTokens, o)
+	return
+}
+
+func newAdjustableTokenChanLimiter(initialTokens uint, maxTokens uint, o options) (tl *AdjustableTokenChanLimiter) {
 	tl = &AdjustableTokenChanLimiter{
 		maxTokenCount: maxTokens,
 	}
+	tl.panicPolicy = o.panicPolicy
 	tl.tokens = make(chan *[16]byte, int(maxTokens))
 	tl.AddTokens(initialTokens)
 	return
 }
 
+/*
+NewAdjustableCpuTokenChanLimiter instantiates a new AdjustableTokenChanLimiter with the provided initial number of tokens and a maximum of the number of CPUs, or the initial number of tokens if that is greater.
+*/
 func NewAdjustableCpuTokenChanLimiter(initialTokens uint) (tl *AdjustableTokenChanLimiter) {
 	maxTokens := uint(runtime.NumCPU())
 	if maxTokens < initialTokens {
@@ -33,51 +71,150 @@ func NewAdjustableCpuTokenChanLimiter(initialTokens uint) (tl *AdjustableTokenCh
 }
 
 /*
-GetTokenCount returns the current token count.
+GetTokenCount returns the current token count. Tokens awaiting retirement after a reduction are not counted.
 */
 func (l *AdjustableTokenChanLimiter) GetTokenCount() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.tokenCount
 }
 
 /*
-AddTokens creates the specified number of new tokens and adds them to the limiter's supply channel.
+PendingShrink returns the number of tokens in use which will be retired as they are released, following a reduction of the token count.
+*/
+func (l *AdjustableTokenChanLimiter) PendingShrink() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.retiring
+}
+
+/*
+AddTokens creates the specified number of new tokens and adds them to the limiter's supply channel. Tokens awaiting retirement are kept instead of being replaced.
 
 If the maximum number of tokens are reached, an error will be returned.
 */
 func (l *AdjustableTokenChanLimiter) AddTokens(count uint) (err error) {
-	if count == 0 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.addTokens(count)
+}
+
+/*
+RemoveTokens removes up to the specified number of tokens from the limiter's supply. It never blocks: tokens which are in use are retired as they are released, and PendingShrink reports how many remain.
+
+If the number of tokens reaches 0, no action will be taken and no error will be returned. Passing math.MaxUint64 instead of a known token count will safely remove all tokens.
+*/
+func (l *AdjustableTokenChanLimiter) RemoveTokens(count uint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeTokens(count)
+	return
+}
+
+/*
+SetTokenCount adds or removes tokens to reach the provided token count. Removal never blocks; tokens in use above the new count are retired as they are released.
+
+If the count exceeds the maximum, tokens are added up to the maximum and an error is returned.
+*/
+func (l *AdjustableTokenChanLimiter) SetTokenCount(count uint) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.setTokenCount(count)
+}
+
+/*
+ReleaseToken notifies the limiter that the provided token (pointer and value) can be used by another goroutine. If tokens are awaiting retirement after a reduction of the token count, the token is retired instead.
+*/
+func (l *AdjustableTokenChanLimiter) ReleaseToken(token *[16]byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.retiring > 0 {
+		l.retiring -= 1
 		return
 	}
-	if l.maxTokenCount <= l.tokenCount {
-		err = errors.New("Token count maximum has been reached.")
+	// tokens in existence never exceed the channel's capacity, so this does not block
+	l.tokens <- token
+}
+
+/*
+Invoke enforces the limiter's limits around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *AdjustableTokenChanLimiter) Invoke(f func() error) (err error) {
+	token := l.AcquireToken()
+	return l.panicPolicy.invoke(f, func(bool) {
+		l.ReleaseToken(token)
+	})
+}
+
+/*
+Reconfigure applies the WithTokens and WithMaxTokens options to this limiter. WithTokens sets the token count as SetTokenCount does. WithMaxTokens may lower the maximum, removing tokens above it, but may not raise it above the maximum set at construction.
+
+An *OptionError is returned, and no changes are made, if any option is invalid.
+*/
+func (l *AdjustableTokenChanLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens", "WithMaxTokens"); err != nil {
 		return
 	}
+	if o.maxTokensSet {
+		if o.maxTokens == 0 {
+			return &OptionError{"WithMaxTokens", ErrInvalidTokenCount}
+		}
+		if o.maxTokens > uint(cap(l.tokens)) {
+			return &OptionError{"WithMaxTokens", ErrNotAdjustable}
+		}
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.tokenCount+count > l.maxTokenCount {
+	maxTokens := l.maxTokenCount
+	if o.maxTokensSet {
+		maxTokens = o.maxTokens
+	}
+	count := l.tokenCount
+	if o.tokensSet {
+		if o.tokens > maxTokens {
+			return &OptionError{"WithTokens", ErrTokenCountExceedsMax}
+		}
+		count = o.tokens
+	} else if count > maxTokens {
+		count = maxTokens
+	}
+	l.maxTokenCount = maxTokens
+	return l.setTokenCount(count)
+}
+
+// addTokens, removeTokens and setTokenCount must be called with mu held.
+func (l *AdjustableTokenChanLimiter) addTokens(count uint) (err error) {
+	if count > l.maxTokenCount-l.tokenCount {
 		err = errors.New("Token count maximum has been reached.")
 	}
 	for i := uint(0); i < count && l.tokenCount < l.maxTokenCount; i += 1 {
-		l.tokens <- new([16]byte)
+		if l.retiring > 0 {
+			l.retiring -= 1
+		} else {
+			l.tokens <- newRawToken()
+		}
 		l.tokenCount += 1
 	}
 	return
 }
 
-/*
-RemoveTokens removes up to the specified number of tokens from the limiter's supply channel.
-
-If the number of tokens reaches 0, no action will be taken and no error will be returned. Passing math.MaxUint64 instead of a known token count will safely remove all tokens.
-*/
-func (l *AdjustableTokenChanLimiter) RemoveTokens(count uint) (err error) {
-	if count == 0 || l.tokenCount == 0 {
-		return
-	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
+func (l *AdjustableTokenChanLimiter) removeTokens(count uint) {
 	for i := uint(0); i < count && l.tokenCount > 0; i += 1 {
-		<-l.tokens
+		select {
+		case <-l.tokens:
+		default:
+			l.retiring += 1
+		}
 		l.tokenCount -= 1
 	}
+}
+
+func (l *AdjustableTokenChanLimiter) setTokenCount(count uint) (err error) {
+	if count > l.tokenCount {
+		err = l.addTokens(count - l.tokenCount)
+	} else if count < l.tokenCount {
+		l.removeTokens(l.tokenCount - count)
+	}
 	return
 }
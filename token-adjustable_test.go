@@ -2,6 +2,7 @@ package limiter
 
 import (
 	"errors"
+	"sync"
 	"testing"
 )
 
@@ -55,6 +56,63 @@ func TestAdjustableTokenChanLimiter_Invoke(t *testing.T) {
 	}
 }
 
+func TestAdjustableTokenChanLimiter_RemoveWhileHeld(t *testing.T) {
+	l := NewAdjustableTokenChanLimiter(2, 4)
+	a := l.AcquireToken()
+	b := l.AcquireToken()
+
+	// removal does not wait for the held tokens, which are retired as they are released
+	l.SetTokenCount(0)
+	if actual := l.PendingShrink(); actual != 2 {
+		t.Errorf("Expected '%d' pending, got '%d'", 2, actual)
+	}
+	l.ReleaseToken(a)
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Error("Expected released token to be retired")
+	}
+
+	// an increase keeps the token still awaiting retirement
+	l.SetTokenCount(1)
+	if actual := l.PendingShrink(); actual != 0 {
+		t.Errorf("Expected '%d' pending, got '%d'", 0, actual)
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Error("Expected the only token to be held")
+	}
+	l.ReleaseToken(b)
+	if _, ok := l.TryAcquireToken(); !ok {
+		t.Error("Expected released token to be available")
+	}
+	if actual := l.GetTokenCount(); actual != 1 {
+		t.Errorf("Expected '%d', got '%d'", 1, actual)
+	}
+}
+
+func TestAdjustableTokenChanLimiter_ConcurrentReconfigure(t *testing.T) {
+	l := NewAdjustableTokenChanLimiter(4, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i += 1 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j += 1 {
+				l.Reconfigure(WithTokens(uint(i + 1)))
+				l.Invoke(func() error { return nil })
+			}
+		}(i)
+	}
+	wg.Wait()
+	l.SetTokenCount(8)
+	for i := 0; i < 8; i += 1 {
+		if _, ok := l.TryAcquireToken(); !ok {
+			t.Fatalf("Expected '%d' tokens, got '%d'", 8, i)
+		}
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Error("Expected no more than the maximum number of tokens")
+	}
+}
+
 func BenchmarkAdjustableTokenChanLimiterSingle(b *testing.B) {
 	l := NewAdjustableTokenChanLimiter(1, 1)
 	for i := 0; i < b.N; i++ {
@@ -6,15 +6,19 @@ TokenFailLimiter combines a TokenLimiter and a FailLimiter to satisfy the TokenA
 type TokenFailLimiter struct {
 	tokenLimiter TokenLimiter
 	failLimiter  FailLimiter
+	panicPolicy  PanicPolicy
 }
 
 /*
 NewTokenFailLimiter instantiates a new TokenFailLimiter with the provided TokenLimiter and FailLimiter.
+
+WithPanicPolicy sets what Invoke does when the invoked function panics.
 */
-func NewTokenFailLimiter(tl TokenLimiter, fl FailLimiter) (l *TokenFailLimiter) {
+func NewTokenFailLimiter(tl TokenLimiter, fl FailLimiter, opts ...Option) (l *TokenFailLimiter) {
 	l = &TokenFailLimiter{
 		tokenLimiter: tl,
 		failLimiter:  fl,
+		panicPolicy:  newOptions(opts).panicPolicy,
 	}
 	return
 }
@@ -36,6 +40,13 @@ func (l *TokenFailLimiter) ReleaseTokenAndReport(token *[16]byte, success bool)
 	l.tokenLimiter.ReleaseToken(token)
 }
 
+/*
+ReleaseToken notifies the limiter that the provided token can be used by another goroutine, without reporting a status, and satisfies the TokenLimiter interface.
+*/
+func (l *TokenFailLimiter) ReleaseToken(token *[16]byte) {
+	l.tokenLimiter.ReleaseToken(token)
+}
+
 /*
 Report can be called outside the context of a rate-limited action to notify the limiter that an error has occurred and that the allowed execution rate should be throttled.
 */
@@ -48,7 +59,7 @@ Invoke enforces the limiter's limits before the invocation of the passed functio
 */
 func (l *TokenFailLimiter) Invoke(f func() error) (err error) {
 	token := l.AcquireToken()
-	err = f()
-	l.ReleaseTokenAndReport(token, err == nil)
-	return
+	return l.panicPolicy.invoke(f, func(success bool) {
+		l.ReleaseTokenAndReport(token, success)
+	})
 }
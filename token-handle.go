@@ -0,0 +1,143 @@
+package limiter
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrTokenReleased = errors.New("Token has already been released.")
+
+// tokenSeq provides the sequential IDs of raw tokens created by this package's limiters.
+var tokenSeq atomic.Uint64
+
+// newRawToken returns a raw token whose first 8 bytes hold a new sequential ID.
+func newRawToken() (token *[16]byte) {
+	token = new([16]byte)
+	binary.BigEndian.PutUint64(token[:8], tokenSeq.Add(1))
+	return
+}
+
+/*
+Token is a handle to a raw token acquired from a TokenLimiter, carrying the token's ID, the time it was acquired, the limiter which owns it and an optional key.
+
+A Token can only be released into the limiter it was acquired from, and only once.
+*/
+type Token struct {
+	mu       sync.Mutex
+	clock    Clock
+	raw      *[16]byte
+	owner    TokenLimiter
+	key      string
+	acquired time.Time
+	released bool
+}
+
+/*
+Acquire blocks until a token can be acquired from the provided TokenLimiter, and returns a Token handle for it.
+
+WithKey sets the Token's key, such as the name of the caller or resource it was acquired for. WithClock sets the Clock used to record acquisition and measure hold duration.
+*/
+func Acquire(l TokenLimiter, opts ...Option) *Token {
+	o := newOptions(opts)
+	raw := l.AcquireToken()
+	return newToken(o, l, raw)
+}
+
+/*
+TryAcquire acquires a token from the provided limiter if one is immediately available, and returns a Token handle for it; otherwise it returns false without blocking. Options are the same as for Acquire.
+*/
+func TryAcquire(l interface {
+	TokenLimiter
+	TryAcquireToken() (*[16]byte, bool)
+}, opts ...Option) (t *Token, ok bool) {
+	o := newOptions(opts)
+	raw, ok := l.TryAcquireToken()
+	if !ok {
+		return
+	}
+	return newToken(o, l, raw), true
+}
+
+func newToken(o options, l TokenLimiter, raw *[16]byte) *Token {
+	return &Token{
+		clock:    o.clock,
+		raw:      raw,
+		owner:    l,
+		key:      o.key,
+		acquired: o.clock.Now(),
+	}
+}
+
+/*
+ID returns the token's ID, which is read from the first 8 bytes of the raw token. Tokens created by this package's limiters have sequential IDs, so the ID identifies which of a limiter's tokens is held.
+*/
+func (t *Token) ID() uint64 {
+	return binary.BigEndian.Uint64(t.raw[:8])
+}
+
+/*
+Key returns the key provided when the token was acquired.
+*/
+func (t *Token) Key() string {
+	return t.key
+}
+
+/*
+Owner returns the limiter the token was acquired from.
+*/
+func (t *Token) Owner() TokenLimiter {
+	return t.owner
+}
+
+/*
+Acquired returns the time at which the token was acquired.
+*/
+func (t *Token) Acquired() time.Time {
+	return t.acquired
+}
+
+/*
+HeldFor returns the duration for which the token has been held.
+*/
+func (t *Token) HeldFor() time.Duration {
+	return t.clock.Now().Sub(t.acquired)
+}
+
+/*
+Release returns the token to the limiter it was acquired from. ErrTokenReleased is returned if the token has already been released.
+*/
+func (t *Token) Release() (err error) {
+	if err = t.markReleased(); err != nil {
+		return
+	}
+	t.owner.ReleaseToken(t.raw)
+	return
+}
+
+/*
+ReleaseAndReport returns the token to the limiter it was acquired from, and reports the success/fail status of the action if the limiter satisfies the TokenAndFailLimiter interface. ErrTokenReleased is returned if the token has already been released.
+*/
+func (t *Token) ReleaseAndReport(success bool) (err error) {
+	if err = t.markReleased(); err != nil {
+		return
+	}
+	if fl, ok := t.owner.(TokenAndFailLimiter); ok {
+		fl.ReleaseTokenAndReport(t.raw, success)
+		return
+	}
+	t.owner.ReleaseToken(t.raw)
+	return
+}
+
+func (t *Token) markReleased() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return ErrTokenReleased
+	}
+	t.released = true
+	return nil
+}
@@ -0,0 +1,66 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+func TestToken(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewTokenChanLimiter(2)
+
+	a := Acquire(l, WithKey("a"), WithClock(c))
+	b, ok := TryAcquire(l, WithClock(c))
+	if !ok {
+		t.Fatal("Expected second token to be acquired")
+	}
+	if _, ok := TryAcquire(l); ok {
+		t.Fatal("Expected no token to be available")
+	}
+	if a.ID() == b.ID() || b.ID() != a.ID()+1 {
+		t.Errorf("Expected sequential IDs, got %d and %d", a.ID(), b.ID())
+	}
+	if a.Key() != "a" || a.Owner() != l || !a.Acquired().Equal(c.Now()) {
+		t.Errorf("Unexpected token metadata %q %v %s", a.Key(), a.Owner(), a.Acquired())
+	}
+
+	c.Advance(3 * time.Second)
+	if d := a.HeldFor(); d != 3*time.Second {
+		t.Errorf("Expected 3s, got %s", d)
+	}
+	if err := a.Release(); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if err := a.Release(); !errors.Is(err, ErrTokenReleased) {
+		t.Errorf("Expected '%v', got '%v'", ErrTokenReleased, err)
+	}
+	if err := a.ReleaseAndReport(true); !errors.Is(err, ErrTokenReleased) {
+		t.Errorf("Expected '%v', got '%v'", ErrTokenReleased, err)
+	}
+	if len(l.tokens) != 1 {
+		t.Errorf("Expected 1 available token, got %d", len(l.tokens))
+	}
+	b.ReleaseAndReport(false)
+	if len(l.tokens) != 2 {
+		t.Errorf("Expected 2 available tokens, got %d", len(l.tokens))
+	}
+}
+
+func TestToken_ReleaseAndReport(t *testing.T) {
+	fl := NewFailBackOffLimiter(backoff.None)
+	l := NewTokenFailLimiter(NewTokenChanLimiter(1), fl)
+
+	token := Acquire(l)
+	token.ReleaseAndReport(false)
+	if fl.failCount != 1 {
+		t.Errorf("Expected 1, got %d", fl.failCount)
+	}
+	token = Acquire(l)
+	token.Release()
+	if fl.failCount != 1 {
+		t.Errorf("Expected Release not to report, got %d", fl.failCount)
+	}
+}
@@ -0,0 +1,177 @@
+package limiter
+
+import (
+	"sync"
+)
+
+/*
+ResizableTokenLimiter limits concurrency with a token supply whose limit can be raised or lowered at any time, and satisfies the TokenLimiter and InvocationLimiter interfaces.
+
+Unlike AdjustableTokenChanLimiter, it has no maximum fixed at construction, and lowering the limit never blocks: tokens held in excess of a lowered limit are retired as they are released. Waiting callers acquire tokens in the order they arrived.
+*/
+type ResizableTokenLimiter struct {
+	mu          sync.Mutex
+	limit       uint
+	inUse       uint
+	free        []*[16]byte
+	waiters     []chan *[16]byte
+	panicPolicy PanicPolicy
+}
+
+/*
+NewResizableTokenLimiter instantiates a new ResizableTokenLimiter with the provided limit on the number of tokens in use.
+*/
+func NewResizableTokenLimiter(limit uint, opts ...Option) (l *ResizableTokenLimiter) {
+	l, err := BuildResizableTokenLimiter(append([]Option{WithTokens(limit)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildResizableTokenLimiter instantiates a new ResizableTokenLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithTokens sets the initial limit, which defaults to 0.
+*/
+func BuildResizableTokenLimiter(opts ...Option) (l *ResizableTokenLimiter, err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens"); err != nil {
+		return
+	}
+	l = &ResizableTokenLimiter{
+		limit:       o.tokens,
+		panicPolicy: o.panicPolicy,
+	}
+	return
+}
+
+/*
+AcquireToken blocks until the number of tokens in use is below the limit and no earlier caller is waiting, then acquires a token. The token must be held for the duration of the activity which needs to be limited, and then it must be passed to the ReleaseToken method without modification.
+*/
+func (l *ResizableTokenLimiter) AcquireToken() (token *[16]byte) {
+	l.mu.Lock()
+	if l.inUse < l.limit && len(l.waiters) == 0 {
+		token = l.take()
+		l.mu.Unlock()
+		return
+	}
+	wait := make(chan *[16]byte, 1)
+	l.waiters = append(l.waiters, wait)
+	l.mu.Unlock()
+	return <-wait
+}
+
+/*
+TryAcquireToken acquires a token if one is immediately available, otherwise it returns false without blocking. A successfully acquired token must be passed to the ReleaseToken method without modification.
+*/
+func (l *ResizableTokenLimiter) TryAcquireToken() (token *[16]byte, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse < l.limit && len(l.waiters) == 0 {
+		token, ok = l.take(), true
+	}
+	return
+}
+
+/*
+ReleaseToken notifies the limiter that the provided token (pointer and value) can be used by another goroutine. If the number of tokens in use exceeds the limit, the token is retired instead.
+*/
+func (l *ResizableTokenLimiter) ReleaseToken(token *[16]byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse > l.limit {
+		l.inUse -= 1
+		return
+	}
+	if len(l.waiters) > 0 {
+		// hand the token directly to the longest waiter, so the number in use is unchanged
+		l.waiters[0] <- token
+		l.waiters = l.waiters[1:]
+		return
+	}
+	l.inUse -= 1
+	l.free = append(l.free, token)
+}
+
+/*
+Invoke enforces the limiter's limits around the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *ResizableTokenLimiter) Invoke(f func() error) (err error) {
+	token := l.AcquireToken()
+	return l.panicPolicy.invoke(f, func(bool) {
+		l.ReleaseToken(token)
+	})
+}
+
+/*
+SetLimit sets the limit on the number of tokens in use. Raising the limit immediately admits waiting callers. Lowering it returns immediately; tokens in use above the new limit are retired as they are released, and PendingShrink reports how many remain.
+*/
+func (l *ResizableTokenLimiter) SetLimit(n uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = n
+	for len(l.waiters) > 0 && l.inUse < l.limit {
+		l.waiters[0] <- l.take()
+		l.waiters = l.waiters[1:]
+	}
+	// free tokens beyond the new limit are retired now
+	if l.inUse >= l.limit {
+		l.free = nil
+	} else if spare := l.limit - l.inUse; uint(len(l.free)) > spare {
+		l.free = l.free[:spare]
+	}
+}
+
+/*
+Limit returns the limit on the number of tokens in use.
+*/
+func (l *ResizableTokenLimiter) Limit() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+/*
+InUse returns the number of tokens which have been acquired and not yet released.
+*/
+func (l *ResizableTokenLimiter) InUse() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inUse
+}
+
+/*
+PendingShrink returns the number of tokens in use above the limit, which will be retired as they are released.
+*/
+func (l *ResizableTokenLimiter) PendingShrink() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse > l.limit {
+		return l.inUse - l.limit
+	}
+	return 0
+}
+
+/*
+Reconfigure applies the WithTokens option to this limiter, setting the limit as SetLimit does.
+*/
+func (l *ResizableTokenLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens"); err != nil {
+		return
+	}
+	if o.tokensSet {
+		l.SetLimit(o.tokens)
+	}
+	return
+}
+
+// take returns a free token, or a new one if none are free, and counts it as in use. The caller must hold mu.
+func (l *ResizableTokenLimiter) take() (token *[16]byte) {
+	l.inUse += 1
+	if n := len(l.free); n > 0 {
+		token = l.free[n-1]
+		l.free = l.free[:n-1]
+		return
+	}
+	return newRawToken()
+}
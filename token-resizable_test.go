@@ -0,0 +1,151 @@
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResizableTokenLimiter(t *testing.T) {
+	l := NewResizableTokenLimiter(2)
+
+	first := l.AcquireToken()
+	second := l.AcquireToken()
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no token, got one")
+	}
+	if actual := l.InUse(); actual != 2 {
+		t.Errorf("Expected '%d' in use, got '%d'", 2, actual)
+	}
+	l.ReleaseToken(first)
+	l.ReleaseToken(second)
+	if actual := l.InUse(); actual != 0 {
+		t.Errorf("Expected '%d' in use, got '%d'", 0, actual)
+	}
+}
+
+func TestResizableTokenLimiter_Invoke(t *testing.T) {
+	l := NewResizableTokenLimiter(1)
+
+	if err := l.Invoke(func() error { return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if err := l.Invoke(func() error { return nil }); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestResizableTokenLimiter_SetLimitGrow(t *testing.T) {
+	l := NewResizableTokenLimiter(1)
+	held := l.AcquireToken()
+
+	acquired := make(chan *[16]byte)
+	for i := 0; i < 2; i += 1 {
+		go func() {
+			acquired <- l.AcquireToken()
+		}()
+	}
+	select {
+	case <-acquired:
+		t.Fatal("Expected acquisition to block at the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.SetLimit(3)
+	tokens := []*[16]byte{<-acquired, <-acquired}
+	if actual := l.InUse(); actual != 3 {
+		t.Errorf("Expected '%d' in use, got '%d'", 3, actual)
+	}
+	l.ReleaseToken(held)
+	for _, token := range tokens {
+		l.ReleaseToken(token)
+	}
+	if actual := l.InUse(); actual != 0 {
+		t.Errorf("Expected '%d' in use, got '%d'", 0, actual)
+	}
+}
+
+func TestResizableTokenLimiter_SetLimitShrink(t *testing.T) {
+	l := NewResizableTokenLimiter(3)
+	tokens := []*[16]byte{l.AcquireToken(), l.AcquireToken(), l.AcquireToken()}
+
+	l.SetLimit(1)
+	if actual := l.Limit(); actual != 1 {
+		t.Errorf("Expected limit '%d', got '%d'", 1, actual)
+	}
+	if actual := l.PendingShrink(); actual != 2 {
+		t.Errorf("Expected pending shrink '%d', got '%d'", 2, actual)
+	}
+
+	l.ReleaseToken(tokens[0])
+	if actual := l.PendingShrink(); actual != 1 {
+		t.Errorf("Expected pending shrink '%d', got '%d'", 1, actual)
+	}
+	l.ReleaseToken(tokens[1])
+	if actual := l.PendingShrink(); actual != 0 {
+		t.Errorf("Expected pending shrink '%d', got '%d'", 0, actual)
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no token while at the limit, got one")
+	}
+
+	l.ReleaseToken(tokens[2])
+	token, ok := l.TryAcquireToken()
+	if !ok {
+		t.Fatal("Expected token, got none")
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no token, got one")
+	}
+	l.ReleaseToken(token)
+}
+
+func TestResizableTokenLimiter_FIFO(t *testing.T) {
+	l := NewResizableTokenLimiter(1)
+	held := l.AcquireToken()
+
+	order := make(chan int, 2)
+	for i := 0; i < 2; i += 1 {
+		go func(i int) {
+			token := l.AcquireToken()
+			order <- i
+			l.ReleaseToken(token)
+		}(i)
+		// wait for each waiter to queue before starting the next
+		for {
+			l.mu.Lock()
+			n := len(l.waiters)
+			l.mu.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	l.ReleaseToken(held)
+	for expected := 0; expected < 2; expected += 1 {
+		if actual := <-order; actual != expected {
+			t.Errorf("Expected waiter '%d', got '%d'", expected, actual)
+		}
+	}
+}
+
+func TestResizableTokenLimiter_Reconfigure(t *testing.T) {
+	l := NewResizableTokenLimiter(1)
+
+	if err := l.Reconfigure(WithTokens(4)); err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+	if actual := l.Limit(); actual != 4 {
+		t.Errorf("Expected limit '%d', got '%d'", 4, actual)
+	}
+}
+
+func BenchmarkResizableTokenLimiter(b *testing.B) {
+	l := NewResizableTokenLimiter(1)
+	for i := 0; i < b.N; i++ {
+		l.ReleaseToken(l.AcquireToken())
+	}
+}
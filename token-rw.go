@@ -0,0 +1,226 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+RWTokenLimiter limits concurrency with shared and exclusive tokens: up to a number of shared tokens may be held at once, or a single exclusive token. It satisfies the TokenLimiter and InvocationLimiter interfaces, where AcquireToken and Invoke use shared tokens.
+
+Writers are preferred: while a caller waits for the exclusive token, no new shared tokens are issued, so a steady stream of readers cannot starve it.
+*/
+type RWTokenLimiter struct {
+	mu             sync.Mutex
+	free           []*[16]byte
+	shared         uint
+	exclusive      *[16]byte
+	exclusiveHeld  bool
+	waitingWriters uint
+	changed        chan struct{}
+	panicPolicy    PanicPolicy
+}
+
+/*
+NewRWTokenLimiter instantiates a new RWTokenLimiter with the provided number of shared tokens.
+
+It panics with an *OptionError if the number is 0; BuildRWTokenLimiter returns the error instead.
+*/
+func NewRWTokenLimiter(sharedTokens uint, opts ...Option) (l *RWTokenLimiter) {
+	l, err := BuildRWTokenLimiter(append([]Option{WithTokens(sharedTokens)}, opts...)...)
+	must(err)
+	return
+}
+
+/*
+BuildRWTokenLimiter instantiates a new RWTokenLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithTokens is required, and sets the number of shared tokens.
+*/
+func BuildRWTokenLimiter(opts ...Option) (l *RWTokenLimiter, err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens"); err != nil {
+		return
+	}
+	if !o.tokensSet {
+		err = &OptionError{"WithTokens", ErrMissingOption}
+		return
+	}
+	if o.tokens == 0 {
+		err = &OptionError{"WithTokens", ErrInvalidTokenCount}
+		return
+	}
+	l = &RWTokenLimiter{
+		free:        make([]*[16]byte, o.tokens),
+		exclusive:   newRawToken(),
+		changed:     make(chan struct{}),
+		panicPolicy: o.panicPolicy,
+	}
+	for i := range l.free {
+		l.free[i] = newRawToken()
+	}
+	return
+}
+
+/*
+AcquireToken blocks until a shared token can be acquired. The token must be held for the duration of the activity which needs to be limited, and then it must be passed to the ReleaseToken method without modification.
+*/
+func (l *RWTokenLimiter) AcquireToken() (token *[16]byte) {
+	token, _ = l.AcquireSharedToken(context.Background())
+	return
+}
+
+/*
+TryAcquireToken acquires a shared token if one is immediately available, otherwise it returns false without blocking.
+*/
+func (l *RWTokenLimiter) TryAcquireToken() (token *[16]byte, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.canShare() {
+		token, ok = l.takeShared(), true
+	}
+	return
+}
+
+/*
+AcquireSharedToken blocks until a shared token can be acquired or the context is done, in which case the context's error is returned. Shared tokens are not issued while the exclusive token is held or awaited.
+*/
+func (l *RWTokenLimiter) AcquireSharedToken(ctx context.Context) (token *[16]byte, err error) {
+	for {
+		l.mu.Lock()
+		if l.canShare() {
+			token = l.takeShared()
+			l.mu.Unlock()
+			return
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+/*
+AcquireExclusiveToken blocks until the exclusive token can be acquired or the context is done, in which case the context's error is returned. The exclusive token is issued when no shared tokens are held.
+*/
+func (l *RWTokenLimiter) AcquireExclusiveToken(ctx context.Context) (token *[16]byte, err error) {
+	l.mu.Lock()
+	l.waitingWriters += 1
+	defer func() {
+		l.waitingWriters -= 1
+		if err != nil {
+			// readers held back by this writer may proceed
+			l.broadcast()
+		}
+		l.mu.Unlock()
+	}()
+	for !l.canExclude() {
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-changed:
+		}
+		l.mu.Lock()
+		if err != nil {
+			return
+		}
+	}
+	l.exclusiveHeld = true
+	token = l.exclusive
+	return
+}
+
+/*
+TryAcquireExclusiveToken acquires the exclusive token if it is immediately available, otherwise it returns false without blocking.
+*/
+func (l *RWTokenLimiter) TryAcquireExclusiveToken() (token *[16]byte, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.canExclude() && l.waitingWriters == 0 {
+		l.exclusiveHeld = true
+		token, ok = l.exclusive, true
+	}
+	return
+}
+
+/*
+ReleaseToken notifies the limiter that the provided shared or exclusive token (pointer and value) can be used by another goroutine. The caller must not modify the value of the token at any time.
+*/
+func (l *RWTokenLimiter) ReleaseToken(token *[16]byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if token == l.exclusive {
+		l.exclusiveHeld = false
+	} else {
+		l.shared -= 1
+		l.free = append(l.free, token)
+	}
+	l.broadcast()
+}
+
+/*
+Invoke holds a shared token for the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *RWTokenLimiter) Invoke(f func() error) (err error) {
+	token := l.AcquireToken()
+	return l.panicPolicy.invoke(f, func(bool) {
+		l.ReleaseToken(token)
+	})
+}
+
+/*
+InvokeExclusive holds the exclusive token for the invocation of the passed function. The error returned by the function invocation is returned to the caller without modification.
+*/
+func (l *RWTokenLimiter) InvokeExclusive(f func() error) (err error) {
+	token, _ := l.AcquireExclusiveToken(context.Background())
+	return l.panicPolicy.invoke(f, func(bool) {
+		l.ReleaseToken(token)
+	})
+}
+
+/*
+Shared returns the number of shared tokens held.
+*/
+func (l *RWTokenLimiter) Shared() uint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.shared
+}
+
+/*
+Exclusive returns true while the exclusive token is held.
+*/
+func (l *RWTokenLimiter) Exclusive() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.exclusiveHeld
+}
+
+// canShare and canExclude report whether a token of each kind may be issued. The caller must hold mu.
+func (l *RWTokenLimiter) canShare() bool {
+	return !l.exclusiveHeld && l.waitingWriters == 0 && len(l.free) > 0
+}
+
+func (l *RWTokenLimiter) canExclude() bool {
+	return !l.exclusiveHeld && l.shared == 0
+}
+
+// takeShared removes a shared token from the free list. The caller must hold mu.
+func (l *RWTokenLimiter) takeShared() (token *[16]byte) {
+	n := len(l.free)
+	token = l.free[n-1]
+	l.free = l.free[:n-1]
+	l.shared += 1
+	return
+}
+
+// broadcast wakes all waiters to re-check their conditions. The caller must hold mu.
+func (l *RWTokenLimiter) broadcast() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
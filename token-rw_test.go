@@ -0,0 +1,143 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRWTokenLimiter(t *testing.T) {
+	l := NewRWTokenLimiter(2)
+
+	first := l.AcquireToken()
+	second := l.AcquireToken()
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no shared token, got one")
+	}
+	if _, ok := l.TryAcquireExclusiveToken(); ok {
+		t.Fatal("Expected no exclusive token while shared tokens are held, got one")
+	}
+	if actual := l.Shared(); actual != 2 {
+		t.Errorf("Expected '%d' shared, got '%d'", 2, actual)
+	}
+	l.ReleaseToken(first)
+	l.ReleaseToken(second)
+
+	token, ok := l.TryAcquireExclusiveToken()
+	if !ok {
+		t.Fatal("Expected exclusive token, got none")
+	}
+	if !l.Exclusive() {
+		t.Error("Expected exclusive token to be held")
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no shared token while exclusive token is held, got one")
+	}
+	l.ReleaseToken(token)
+	if l.Exclusive() {
+		t.Error("Expected exclusive token to be released")
+	}
+}
+
+func TestRWTokenLimiter_Invoke(t *testing.T) {
+	l := NewRWTokenLimiter(1)
+
+	if err := l.Invoke(func() error { return errors.New("error") }); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if err := l.InvokeExclusive(func() error {
+		if l.Shared() != 0 || !l.Exclusive() {
+			t.Error("Expected exclusive token to be held alone")
+		}
+		return nil
+	}); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestRWTokenLimiter_WriterPreference(t *testing.T) {
+	l := NewRWTokenLimiter(4)
+	reader := l.AcquireToken()
+
+	writer := make(chan *[16]byte)
+	go func() {
+		token, _ := l.AcquireExclusiveToken(context.Background())
+		writer <- token
+	}()
+	waitForRWWriters(l, 1)
+
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no shared token while a writer waits, got one")
+	}
+	lateReader := make(chan *[16]byte)
+	go func() {
+		lateReader <- l.AcquireToken()
+	}()
+
+	l.ReleaseToken(reader)
+	token := <-writer
+	select {
+	case <-lateReader:
+		t.Fatal("Expected reader to wait for the writer")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.ReleaseToken(token)
+	l.ReleaseToken(<-lateReader)
+}
+
+func TestRWTokenLimiter_Cancel(t *testing.T) {
+	l := NewRWTokenLimiter(1)
+	reader := l.AcquireToken()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		_, err := l.AcquireExclusiveToken(ctx)
+		result <- err
+	}()
+	waitForRWWriters(l, 1)
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+
+	l.ReleaseToken(reader)
+	if _, ok := l.TryAcquireToken(); !ok {
+		t.Error("Expected shared token after cancelled writer, got none")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.AcquireSharedToken(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+}
+
+func TestBuildRWTokenLimiter(t *testing.T) {
+	if _, err := BuildRWTokenLimiter(); !errors.Is(err, ErrMissingOption) {
+		t.Errorf("Expected '%v', got '%v'", ErrMissingOption, err)
+	}
+	if _, err := BuildRWTokenLimiter(WithTokens(0)); !errors.Is(err, ErrInvalidTokenCount) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidTokenCount, err)
+	}
+}
+
+func waitForRWWriters(l *RWTokenLimiter, n uint) {
+	for {
+		l.mu.Lock()
+		waiting := l.waitingWriters
+		l.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func BenchmarkRWTokenLimiterShared(b *testing.B) {
+	l := NewRWTokenLimiter(1)
+	for i := 0; i < b.N; i++ {
+		l.ReleaseToken(l.AcquireToken())
+	}
+}
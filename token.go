@@ -8,16 +8,48 @@ import (
 TokenChanLimiter enforces a concurrency limit using tokens and satisfies the TokenLimiter and InvocationLimiter interfaces.
 */
 type TokenChanLimiter struct {
-	mu     sync.Mutex
-	tokens chan *[16]byte
+	mu          sync.Mutex
+	tokens      chan *[16]byte
+	panicPolicy PanicPolicy
 }
 
 /*
 NewTokenChanLimiter instantiates a new TokenChanLimiter with the provided number of tokens.
+
+The number of tokens is not validated, for compatibility with earlier versions: with 0 tokens, every call to AcquireToken blocks forever. BuildTokenChanLimiter rejects it. NewTokenChanLimiter panics with an *OptionError only if an option is invalid.
+*/
+func NewTokenChanLimiter(initialTokens uint, opts ...Option) (l *TokenChanLimiter) {
+	o := newOptions(opts)
+	must(o.validateApplicable())
+	return newTokenChanLimiter(initialTokens, o)
+}
+
+/*
+BuildTokenChanLimiter instantiates a new TokenChanLimiter configured by the provided options, or returns an *OptionError if they are invalid.
+
+WithTokens is required.
 */
-func NewTokenChanLimiter(initialTokens uint) (l *TokenChanLimiter) {
+func BuildTokenChanLimiter(opts ...Option) (l *TokenChanLimiter, err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens"); err != nil {
+		return
+	}
+	if !o.tokensSet {
+		err = &OptionError{"WithTokens", ErrMissingOption}
+		return
+	}
+	if o.tokens == 0 {
+		err = &OptionError{"WithTokens", ErrInvalidTokenCount}
+		return
+	}
+	l = newTokenChanLimiter(o.tokens, o)
+	return
+}
+
+func newTokenChanLimiter(tokens uint, o options) (l *TokenChanLimiter) {
 	l = &TokenChanLimiter{
-		tokens: make(chan *[16]byte, initialTokens),
+		tokens:      make(chan *[16]byte, tokens),
+		panicPolicy: o.panicPolicy,
 	}
 	fillTokenChan(l.tokens)
 	return
@@ -33,6 +65,18 @@ func (l *TokenChanLimiter) AcquireToken() (token *[16]byte) {
 	}
 }
 
+/*
+TryAcquireToken acquires a token from the limiter's supply if one is immediately available, otherwise it returns false without blocking. A successfully acquired token must be passed to the ReleaseToken method without modification.
+*/
+func (l *TokenChanLimiter) TryAcquireToken() (token *[16]byte, ok bool) {
+	select {
+	case token = <-l.tokens:
+		ok = true
+	default:
+	}
+	return
+}
+
 /*
 ReleaseToken notifies the limiter that the provided token (pointer and value) can be used by another goroutine. The caller must not modify the value of the token at any time, but if the token implementation is known by the caller then unmarshaling of its value is not discouraged.
 */
@@ -45,15 +89,29 @@ Invoke enforces the limiter's limits around the invocation of the passed functio
 */
 func (l *TokenChanLimiter) Invoke(f func() error) (err error) {
 	token := l.AcquireToken()
-	err = f()
-	l.ReleaseToken(token)
+	return l.panicPolicy.invoke(f, func(bool) {
+		l.ReleaseToken(token)
+	})
+}
+
+/*
+Reconfigure returns an *OptionError wrapping ErrNotAdjustable if the WithTokens option differs from the limiter's token count, which is fixed at construction; use AdjustableTokenChanLimiter for a variable token count.
+*/
+func (l *TokenChanLimiter) Reconfigure(opts ...Option) (err error) {
+	o := newOptions(opts)
+	if err = o.validateApplicable("WithTokens"); err != nil {
+		return
+	}
+	if o.tokensSet && o.tokens != uint(cap(l.tokens)) {
+		err = &OptionError{"WithTokens", ErrNotAdjustable}
+	}
 	return
 }
 
 func fillTokenChan(c chan *[16]byte) {
 	capacity := cap(c)
 	for i := 0; i < capacity; i += 1 {
-		c <- new([16]byte)
+		c <- newRawToken()
 	}
 	return
 }
@@ -24,6 +24,22 @@ func TestTokenChanLimiter_Invoke(t *testing.T) {
 	}
 }
 
+func TestTokenChanLimiter_TryAcquireToken(t *testing.T) {
+	l := NewTokenChanLimiter(1)
+
+	token, ok := l.TryAcquireToken()
+	if !ok {
+		t.Fatal("Expected token, got none")
+	}
+	if _, ok := l.TryAcquireToken(); ok {
+		t.Fatal("Expected no token, got one")
+	}
+	l.ReleaseToken(token)
+	if _, ok := l.TryAcquireToken(); !ok {
+		t.Fatal("Expected token, got none")
+	}
+}
+
 func BenchmarkTokenChanLimiterSingle(b *testing.B) {
 	l := NewTokenChanLimiter(1)
 	for i := 0; i < b.N; i++ {
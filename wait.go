@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"context"
+)
+
+/*
+AcquireTokenContext acquires a token from the TokenLimiter unless the context is done first, in which case the context's error is returned.
+
+A token is taken without waiting if the limiter has a TryAcquireToken method and one is available. Otherwise AcquireToken is called by a goroutine which remains until it acquires a token; if the context was done by then, the token is released.
+*/
+func AcquireTokenContext(ctx context.Context, l TokenLimiter) (token *[16]byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if t, ok := l.(interface{ TryAcquireToken() (*[16]byte, bool) }); ok {
+		if token, ok = t.TryAcquireToken(); ok {
+			return
+		}
+	}
+	acquired := make(chan *[16]byte)
+	go func() {
+		token := l.AcquireToken()
+		select {
+		case acquired <- token:
+		case <-ctx.Done():
+			l.ReleaseToken(token)
+		}
+	}()
+	select {
+	case token = <-acquired:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+/*
+CheckWaitContext waits as the RateLimiter's CheckWait does, unless the context is done first, in which case the context's error is returned. A FailLimiter may also be passed.
+
+If the limiter has a WaitN method, like BucketRateLimiter, it is called with a quantity of 1, so that a canceled wait returns its budget. Otherwise CheckWait is called by a goroutine which remains until it returns, and the rate budget it consumes is not returned.
+*/
+func CheckWaitContext(ctx context.Context, l RateLimiter) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if w, ok := l.(interface {
+		WaitN(ctx context.Context, n int) error
+	}); ok {
+		return w.WaitN(ctx, 1)
+	}
+	done := make(chan struct{})
+	go func() {
+		l.CheckWait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
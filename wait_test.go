@@ -0,0 +1,53 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireTokenContext(t *testing.T) {
+	tl := NewTokenChanLimiter(1)
+	token, err := AcquireTokenContext(context.Background(), tl)
+	if err != nil {
+		t.Fatalf("Unexpected error, got: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := AcquireTokenContext(ctx, tl); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected '%v', got '%v'", context.Canceled, err)
+	}
+
+	// the token acquired after cancellation is released by the waiting goroutine
+	tl.ReleaseToken(token)
+	if _, err := AcquireTokenContext(context.Background(), tl); err != nil {
+		t.Errorf("Unexpected error, got: %s", err.Error())
+	}
+}
+
+func TestCheckWaitContext(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	for _, l := range []RateLimiter{
+		NewBucketRateLimiter(NewRate(1, time.Second), 0, WithClock(c)),
+		NewFixedIntervalLimiter(time.Second, WithClock(c)),
+	} {
+		if err := CheckWaitContext(context.Background(), l); err != nil {
+			t.Fatalf("%T: unexpected error, got: %s", l, err.Error())
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			c.BlockUntil(1)
+			cancel()
+		}()
+		if err := CheckWaitContext(ctx, l); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T: expected '%v', got '%v'", l, context.Canceled, err)
+		}
+		// release a wait which continues in the background
+		c.Advance(time.Second)
+	}
+}
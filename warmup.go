@@ -0,0 +1,249 @@
+package limiter
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRamp = errors.New("Ramp floor must not exceed its maximum, and its period must be greater than zero.")
+
+/*
+RampShape determines how a WarmUp's level increases over its period.
+*/
+type RampShape int
+
+const (
+	// LinearRamp increases the level by equal amounts over the period.
+	LinearRamp RampShape = iota
+	// ExponentialRamp increases the level by equal factors over the period, so it rises slowly at first and quickly at the end.
+	ExponentialRamp
+)
+
+// warmUpSteps is the number of increments in which a ramp is applied over its period.
+const warmUpSteps = 20
+
+/*
+WarmUp ramps a limiter's rate or token count from a floor to its maximum over a period, so that cold downstream caches are not overwhelmed by full traffic after a deploy or a recovery.
+
+The ramp begins when Start is called and is applied in 20 equal steps of the period. To ramp up again after a failing dependency recovers, pass Start to the SetRecoverFunc method of a FailBackOffLimiter.
+*/
+type WarmUp struct {
+	mu      sync.Mutex
+	applyMu sync.Mutex
+	clock   Clock
+	shape   RampShape
+	period  time.Duration
+	floor   float64
+	max     float64
+	apply   func(level float64)
+	gen     uint64
+	start   time.Time
+	timer   Timer
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+/*
+NewRateWarmUp instantiates a new WarmUp which ramps the rate of the provided RateSetter, such as a BurstRateLimiter or BucketRateLimiter, from the floor rate to the maximum rate over the period.
+
+Ramped rates have the maximum rate's duration, with counts scaled from the floor rate's equivalent count.
+
+It panics if the arguments or options are invalid; BuildRateWarmUp returns the error instead.
+*/
+func NewRateWarmUp(s RateSetter, floor Rate, max Rate, period time.Duration, opts ...Option) (w *WarmUp) {
+	w, err := BuildRateWarmUp(s, floor, max, period, opts...)
+	must(err)
+	return
+}
+
+/*
+BuildRateWarmUp instantiates a new WarmUp as NewRateWarmUp does, or returns an error if the rates are invalid, the floor exceeds the maximum, the period is not positive, or an option is invalid.
+*/
+func BuildRateWarmUp(s RateSetter, floor Rate, max Rate, period time.Duration, opts ...Option) (w *WarmUp, err error) {
+	if err = firstError(floor.Validate(), max.Validate()); err != nil {
+		return
+	}
+	return buildWarmUp(floor.Per(max.Duration), float64(max.Count), period, func(level float64) {
+		count := int(math.Round(level))
+		if count < 1 {
+			count = 1
+		}
+		s.SetMaxRate(NewRate(count, max.Duration))
+	}, opts)
+}
+
+/*
+NewTokenWarmUp instantiates a new WarmUp which ramps the token count of the provided AdjustableTokenChanLimiter from the floor to the maximum over the period.
+
+Lowering the token count to the floor when a ramp starts does not wait for tokens in use; they are retired as they are released.
+
+It panics if the arguments or options are invalid; BuildTokenWarmUp returns the error instead.
+*/
+func NewTokenWarmUp(l *AdjustableTokenChanLimiter, floor uint, max uint, period time.Duration, opts ...Option) (w *WarmUp) {
+	w, err := BuildTokenWarmUp(l, floor, max, period, opts...)
+	must(err)
+	return
+}
+
+/*
+BuildTokenWarmUp instantiates a new WarmUp as NewTokenWarmUp does, or returns an error if the floor exceeds the maximum, the period is not positive, or an option is invalid.
+*/
+func BuildTokenWarmUp(l *AdjustableTokenChanLimiter, floor uint, max uint, period time.Duration, opts ...Option) (w *WarmUp, err error) {
+	return buildWarmUp(float64(floor), float64(max), period, func(level float64) {
+		l.SetTokenCount(uint(math.Round(level)))
+	}, opts)
+}
+
+func buildWarmUp(floor, max float64, period time.Duration, apply func(float64), opts []Option) (w *WarmUp, err error) {
+	o := newOptions(opts)
+	if err = firstError(o.validateApplicable("WithRampShape"), o.validateClock()); err != nil {
+		return
+	}
+	if floor > max || period <= 0 {
+		err = ErrInvalidRamp
+		return
+	}
+	w = &WarmUp{
+		clock:  o.clock,
+		shape:  o.rampShape,
+		period: period,
+		floor:  floor,
+		max:    max,
+		apply:  apply,
+	}
+	return
+}
+
+/*
+Start begins a ramp from the floor, restarting it if a ramp is already in progress. It never blocks: a step of the previous ramp which is being applied completes before the new ramp's first step is applied, but Start does not wait for it. This allows Start to be called by a FailBackOffLimiter's recover function while the caller holds a token.
+*/
+func (w *WarmUp) Start() {
+	w.mu.Lock()
+	w.cancelRamp()
+	gen := w.gen
+	w.start = w.clock.Now()
+	stop, done := make(chan struct{}), make(chan struct{})
+	w.stop, w.done = stop, done
+	w.mu.Unlock()
+	go w.run(gen, stop, done)
+}
+
+/*
+Stop ends any ramp in progress and applies the maximum, waiting for a step of the ramp which is being applied to complete first.
+*/
+func (w *WarmUp) Stop() {
+	w.mu.Lock()
+	done := w.done
+	w.cancelRamp()
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	w.applyMu.Lock()
+	defer w.applyMu.Unlock()
+	w.apply(w.max)
+}
+
+/*
+Active returns true while a ramp is in progress.
+*/
+func (w *WarmUp) Active() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stop != nil
+}
+
+/*
+Progress returns the fraction of the current ramp's period which has elapsed, or 1 if no ramp is in progress.
+*/
+func (w *WarmUp) Progress() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop == nil || w.period <= 0 {
+		return 1
+	}
+	return math.Min(float64(w.clock.Now().Sub(w.start))/float64(w.period), 1)
+}
+
+/*
+Level returns the rate count or token count applied at the provided fraction of the ramp's period.
+*/
+func (w *WarmUp) Level(fraction float64) float64 {
+	switch {
+	case fraction <= 0:
+		return w.floor
+	case fraction >= 1:
+		return w.max
+	case w.shape == ExponentialRamp:
+		floor := math.Max(w.floor, 1)
+		return math.Min(floor*math.Pow(w.max/floor, fraction), w.max)
+	}
+	return w.floor + (w.max-w.floor)*fraction
+}
+
+// cancelRamp signals the current ramp's goroutine to exit and releases its timer, without waiting for it. The caller must hold mu.
+func (w *WarmUp) cancelRamp() {
+	w.gen += 1
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.stop != nil {
+		close(w.stop)
+	}
+	w.stop, w.done = nil, nil
+}
+
+// newTimer creates the timer for the ramp's next step, or returns nil if the ramp has been restarted or stopped.
+func (w *WarmUp) newTimer(gen uint64, d time.Duration) (t Timer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gen != gen {
+		return nil
+	}
+	t = w.clock.NewTimer(d)
+	w.timer = t
+	return
+}
+
+func (w *WarmUp) run(gen uint64, stop, done chan struct{}) {
+	defer close(done)
+	step := w.period / warmUpSteps
+	for i := 0; i <= warmUpSteps; i += 1 {
+		if i > 0 {
+			timer := w.newTimer(gen, step)
+			if timer == nil {
+				return
+			}
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+		if !w.applyStep(gen, float64(i)/warmUpSteps) {
+			return
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gen == gen {
+		w.stop, w.done, w.timer = nil, nil, nil
+	}
+}
+
+// applyStep applies the level for the fraction unless the ramp has been restarted or stopped, returning false if it has.
+func (w *WarmUp) applyStep(gen uint64, fraction float64) bool {
+	w.applyMu.Lock()
+	defer w.applyMu.Unlock()
+	w.mu.Lock()
+	current := w.gen == gen
+	w.mu.Unlock()
+	if current {
+		w.apply(w.Level(fraction))
+	}
+	return current
+}
@@ -0,0 +1,169 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/momokatte/go-backoff"
+)
+
+type lockedRateSetter struct {
+	mu   sync.Mutex
+	rate Rate
+}
+
+func (s *lockedRateSetter) SetMaxRate(rate Rate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = rate
+}
+
+func (s *lockedRateSetter) waitFor(t *testing.T, expected Rate) {
+	t.Helper()
+	var rate Rate
+	for i := 0; i < 1000; i += 1 {
+		s.mu.Lock()
+		rate = s.rate
+		s.mu.Unlock()
+		if rate == expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %v, got %v", expected, rate)
+}
+
+func TestRateWarmUp(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &lockedRateSetter{}
+	w := NewRateWarmUp(s, NewRate(1, 100*time.Millisecond), NewRate(100, time.Second), 20*time.Second, WithClock(c))
+
+	w.Start()
+	s.waitFor(t, NewRate(10, time.Second))
+	if !w.Active() {
+		t.Error("Expected ramp to be active")
+	}
+	for i := 0; i < 10; i += 1 {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+	}
+	s.waitFor(t, NewRate(55, time.Second))
+	if p := w.Progress(); p != 0.5 {
+		t.Errorf("Expected progress of 0.5, got %f", p)
+	}
+
+	// a recovered dependency restarts the ramp from the floor
+	fl := NewFailBackOffLimiter(backoff.None)
+	fl.SetRecoverFunc(w.Start)
+	fl.Report(false)
+	fl.Report(true)
+	s.waitFor(t, NewRate(10, time.Second))
+
+	for i := 0; i < 20; i += 1 {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+	}
+	s.waitFor(t, NewRate(100, time.Second))
+	for w.Active() {
+		time.Sleep(time.Millisecond)
+	}
+	if c.Sleepers() != 0 {
+		t.Errorf("Expected no pending timers, got %d", c.Sleepers())
+	}
+}
+
+func TestTokenWarmUp(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	tl := NewAdjustableTokenChanLimiter(10, 10)
+	w := NewTokenWarmUp(tl, 2, 10, 20*time.Second, WithClock(c), WithRampShape(ExponentialRamp))
+
+	w.Start()
+	c.BlockUntil(1)
+	w.Stop()
+	if w.Active() {
+		t.Error("Expected ramp to be stopped")
+	}
+	if n := tl.GetTokenCount(); n != 10 {
+		t.Errorf("Expected 10 tokens, got %d", n)
+	}
+	if c.Sleepers() != 0 {
+		t.Errorf("Expected no pending timers, got %d", c.Sleepers())
+	}
+
+	w.Start()
+	for i := 0; i < 20; i += 1 {
+		c.BlockUntil(1)
+		c.Advance(time.Second)
+	}
+	for w.Active() {
+		time.Sleep(time.Millisecond)
+	}
+	if n := tl.GetTokenCount(); n != 10 {
+		t.Errorf("Expected 10 tokens, got %d", n)
+	}
+}
+
+func TestTokenWarmUp_RecoverWhileHoldingToken(t *testing.T) {
+	c := NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	tl := NewAdjustableTokenChanLimiter(1, 1)
+	w := NewTokenWarmUp(tl, 0, 1, 20*time.Second, WithClock(c))
+	fl := NewFailBackOffLimiter(backoff.None)
+	fl.SetRecoverFunc(w.Start)
+	l := NewTokenFailLimiter(tl, fl)
+	fl.Report(false)
+
+	// the recovery starts a ramp which lowers the token count while the caller still holds the only token
+	done := make(chan struct{})
+	go func() {
+		l.Invoke(func() error { return nil })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Invoke to return while the ramp starts")
+	}
+	w.Stop()
+	if n := tl.GetTokenCount(); n != 1 {
+		t.Errorf("Expected 1 token, got %d", n)
+	}
+}
+
+func TestBuildTokenWarmUp_Invalid(t *testing.T) {
+	if _, err := BuildTokenWarmUp(nil, 10, 5, time.Second); err != ErrInvalidRamp {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidRamp, err)
+	}
+	if _, err := BuildTokenWarmUp(nil, 1, 5, 0); err != ErrInvalidRamp {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidRamp, err)
+	}
+	if _, err := BuildRateWarmUp(nil, NewRate(10, time.Second), NewRate(5, time.Second), time.Second); err != ErrInvalidRamp {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidRamp, err)
+	}
+	if _, err := BuildRateWarmUp(nil, NewRate(1, time.Second), NewRate(5, time.Second), time.Second, WithClock(nil)); err == nil {
+		t.Error("Expected error for nil clock, got none")
+	}
+}
+
+func TestWarmUp_Level(t *testing.T) {
+	linear := NewTokenWarmUp(nil, 10, 100, time.Second)
+	exponential := NewTokenWarmUp(nil, 1, 100, time.Second, WithRampShape(ExponentialRamp))
+	tests := []struct {
+		w        *WarmUp
+		fraction float64
+		expected float64
+	}{
+		{linear, -1, 10},
+		{linear, 0.5, 55},
+		{linear, 2, 100},
+		{exponential, 0, 1},
+		{exponential, 0.5, 10},
+		{exponential, 1, 100},
+	}
+	for _, test := range tests {
+		if level := test.w.Level(test.fraction); math.Abs(level-test.expected) > 1e-9 {
+			t.Errorf("%f: expected %f, got %f", test.fraction, test.expected, level)
+		}
+	}
+}